@@ -1,3 +0,0 @@
-package constants
-
-const DESCRIPTION = "description"
\ No newline at end of file
@@ -0,0 +1,435 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"log"
+	"net/http"
+	"net/url"
+	"savannah-store/auth-service/internal/library"
+	"savannah-store/auth-service/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const grantTypeClientCredentials = "client_credentials"
+
+// newClientSecret returns a secret, its sha256 for storage and the hint shown in listings
+func newClientSecret() (secret, hash, hint string, err error) {
+	secret, err = library.RandomToken(32)
+	if err != nil {
+		return "", "", "", err
+	}
+	return secret, library.HashToken(secret), secret[len(secret)-4:], nil
+}
+
+// clientScopes checks that every scope is an existing module:action permission and returns them deduplicated
+func clientScopes(c echo.Context, db *sql.DB, scopes []string) ([]string, *echo.HTTPError) {
+	if len(scopes) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "at least one scope is required")
+	}
+
+	rows, err := db.QueryContext(c.Request().Context(), `
+		SELECT CONCAT(m.name, ':', p.action)
+		FROM permissions p
+		JOIN modules m ON m.id = p.module_id`)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+	known := map[string]bool{}
+	for rows.Next() {
+		var perm string
+		if err := rows.Scan(&perm); err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		known[perm] = true
+	}
+
+	seen := map[string]bool{}
+	granted := []string{}
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !known[scope] {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "unknown scope "+scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			granted = append(granted, scope)
+		}
+	}
+	sort.Strings(granted)
+	return granted, nil
+}
+
+// CreateClient registers a machine client. The secret is only ever shown in this response.
+func CreateClient(c echo.Context, db *sql.DB) error {
+	ctx := c.Request().Context()
+
+	req := new(models.ClientRequest)
+	if err := c.Bind(req); err != nil || strings.TrimSpace(req.Name) == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "name is required"})
+	}
+	scopes, httpErr := clientScopes(c, db, req.Scopes)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+
+	clientID, err := library.RandomToken(12)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to generate client id"})
+	}
+	secret, hash, hint, err := newClientSecret()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to generate secret"})
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO oauth_clients (client_id, name, scopes, created_by) VALUES (?, ?, ?, ?)`,
+		clientID, strings.TrimSpace(req.Name), strings.Join(scopes, " "), c.Get("user_id").(int64))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	id, _ := res.LastInsertId()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO oauth_client_secrets (client_id, secret_hash, hint) VALUES (?, ?, ?)`, id, hash, hint); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	client, err := loadClient(c, db, clientID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	client.ClientSecret = secret
+	return c.JSON(http.StatusCreated, client)
+}
+
+// ListClients returns every client with its live secrets
+func ListClients(c echo.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(c.Request().Context(), `SELECT client_id FROM oauth_clients ORDER BY id`)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	clients := []models.OAuthClient{}
+	for _, id := range ids {
+		client, err := loadClient(c, db, id)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		clients = append(clients, *client)
+	}
+	return c.JSON(http.StatusOK, clients)
+}
+
+func loadClient(c echo.Context, db *sql.DB, clientID string) (*models.OAuthClient, error) {
+	ctx := c.Request().Context()
+
+	var (
+		id                   int64
+		scopes               string
+		createdBy            sql.NullInt64
+		lastUsed, disabledAt sql.NullTime
+	)
+	client := &models.OAuthClient{ClientID: clientID, Secrets: []models.ClientSecret{}}
+	err := db.QueryRowContext(ctx, `
+		SELECT id, name, scopes, created_by, created, last_used_at, disabled_at
+		FROM oauth_clients WHERE client_id = ?`, clientID,
+	).Scan(&id, &client.Name, &scopes, &createdBy, &client.Created, &lastUsed, &disabledAt)
+	if err != nil {
+		return nil, err
+	}
+	client.Scopes = strings.Fields(scopes)
+	client.CreatedBy = createdBy.Int64
+	if lastUsed.Valid {
+		client.LastUsedAt = &lastUsed.Time
+	}
+	if disabledAt.Valid {
+		client.DisabledAt = &disabledAt.Time
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, hint, created, expires_at, last_used_at
+		FROM oauth_client_secrets
+		WHERE client_id = ? AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			s                   models.ClientSecret
+			expires, secretUsed sql.NullTime
+		)
+		if err := rows.Scan(&s.ID, &s.Hint, &s.Created, &expires, &secretUsed); err != nil {
+			return nil, err
+		}
+		if expires.Valid {
+			s.ExpiresAt = &expires.Time
+		}
+		if secretUsed.Valid {
+			s.LastUsedAt = &secretUsed.Time
+		}
+		client.Secrets = append(client.Secrets, s)
+	}
+	return client, rows.Err()
+}
+
+// UpdateClient replaces the name and scopes of a client. Tokens already issued are revoked so a
+// narrowed scope applies at once.
+func UpdateClient(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	clientID := c.Param("client_id")
+
+	req := new(models.ClientRequest)
+	if err := c.Bind(req); err != nil || strings.TrimSpace(req.Name) == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "name is required"})
+	}
+	scopes, httpErr := clientScopes(c, db, req.Scopes)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+
+	res, err := db.ExecContext(c.Request().Context(), `UPDATE oauth_clients SET name = ?, scopes = ? WHERE client_id = ?`,
+		strings.TrimSpace(req.Name), strings.Join(scopes, " "), clientID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := loadClient(c, db, clientID); err == sql.ErrNoRows {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": "client not found"})
+		}
+	}
+	if err := library.RevokeAllClientTokens(rdb, clientID); err != nil {
+		log.Println("failed to revoke client tokens:", err)
+	}
+
+	client, err := loadClient(c, db, clientID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, client)
+}
+
+// RotateClientSecret adds a new secret to a client. The current secrets keep working for
+// CLIENT_SECRET_GRACE_PERIOD, or stop right away (revoking their tokens) with ?immediate=true.
+func RotateClientSecret(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	ctx := c.Request().Context()
+	clientID := c.Param("client_id")
+	immediate, _ := strconv.ParseBool(c.QueryParam("immediate"))
+
+	var id int64
+	err := db.QueryRowContext(ctx, `SELECT id FROM oauth_clients WHERE client_id = ? AND disabled_at IS NULL`, clientID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "client not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	secret, hash, hint, err := newClientSecret()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to generate secret"})
+	}
+
+	expiresAt := time.Now().UTC().Add(library.ClientSecretGracePeriod())
+	if immediate {
+		expiresAt = time.Now().UTC()
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE oauth_client_secrets SET expires_at = ?
+		WHERE client_id = ? AND (expires_at IS NULL OR expires_at > ?)`, expiresAt, id, expiresAt)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO oauth_client_secrets (client_id, secret_hash, hint) VALUES (?, ?, ?)`, id, hash, hint); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	if immediate {
+		if err := library.RevokeAllClientTokens(rdb, clientID); err != nil {
+			log.Println("failed to revoke client tokens:", err)
+		}
+	}
+
+	client, err := loadClient(c, db, clientID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	client.ClientSecret = secret
+	return c.JSON(http.StatusOK, client)
+}
+
+// DisableClient stops a client from getting tokens and revokes the ones it has
+func DisableClient(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	clientID := c.Param("client_id")
+
+	res, err := db.ExecContext(c.Request().Context(),
+		`UPDATE oauth_clients SET disabled_at = NOW() WHERE client_id = ? AND disabled_at IS NULL`, clientID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "client not found or already disabled"})
+	}
+	if err := library.RevokeAllClientTokens(rdb, clientID); err != nil {
+		log.Println("failed to revoke client tokens:", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to revoke tokens"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "client disabled"})
+}
+
+// oauthError answers the token endpoint in the RFC 6749 section 5.2 format
+func oauthError(c echo.Context, status int, code, description string) error {
+	return c.JSON(status, echo.Map{"error": code, "error_description": description})
+}
+
+// ClientToken is the token endpoint of the client credentials grant. The token carries the client's
+// scopes as perms, so the catalog and order services authorize it like a user token.
+func ClientToken(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	ctx := c.Request().Context()
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	req := new(models.ClientTokenRequest)
+	if err := c.Bind(req); err != nil {
+		return oauthError(c, http.StatusBadRequest, "invalid_request", "invalid request")
+	}
+	if req.GrantType != grantTypeClientCredentials {
+		return oauthError(c, http.StatusBadRequest, "unsupported_grant_type", "only client_credentials is supported")
+	}
+
+	// Basic credentials are form encoded before they are joined (RFC 6749 section 2.3.1)
+	basicID, basicSecret, basic := c.Request().BasicAuth()
+	if basic {
+		req.ClientID, _ = url.QueryUnescape(basicID)
+		req.ClientSecret, _ = url.QueryUnescape(basicSecret)
+	}
+	if req.ClientID == "" || req.ClientSecret == "" {
+		return oauthError(c, http.StatusBadRequest, "invalid_request", "client_id and client_secret are required")
+	}
+
+	id, secretID, scopes, ok, err := authenticateClient(c, db, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return oauthError(c, http.StatusInternalServerError, "server_error", "failed to check client")
+	}
+	if !ok {
+		if basic {
+			c.Response().Header().Set("WWW-Authenticate", `Basic realm="savannah-store"`)
+		}
+		return oauthError(c, http.StatusUnauthorized, "invalid_client", "unknown client or wrong secret")
+	}
+
+	granted := scopes
+	if requested := strings.Fields(req.Scope); len(requested) > 0 {
+		allowed := map[string]bool{}
+		for _, s := range scopes {
+			allowed[s] = true
+		}
+		for _, s := range requested {
+			if !allowed[s] {
+				return oauthError(c, http.StatusBadRequest, "invalid_scope", "scope "+s+" is not granted to this client")
+			}
+		}
+		granted = requested
+	}
+
+	if _, err := db.ExecContext(ctx, `UPDATE oauth_clients SET last_used_at = NOW() WHERE id = ?`, id); err != nil {
+		log.Println("failed to record client use:", err)
+	}
+	if _, err := db.ExecContext(ctx, `UPDATE oauth_client_secrets SET last_used_at = NOW() WHERE id = ?`, secretID); err != nil {
+		log.Println("failed to record client secret use:", err)
+	}
+
+	library.AwaitClientRevocationSecond(rdb, req.ClientID)
+	jti, err := library.RandomToken(16)
+	if err != nil {
+		return oauthError(c, http.StatusInternalServerError, "server_error", "failed to generate token")
+	}
+	now := time.Now()
+	ttl := library.AccessTokenTTL()
+	claims := &models.JwtCustomClaims{
+		ClientID: req.ClientID,
+		Perms:    granted,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   "client:" + req.ClientID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	token, err := library.SignToken(claims)
+	if err != nil {
+		return oauthError(c, http.StatusInternalServerError, "server_error", "failed to sign token")
+	}
+
+	return c.JSON(http.StatusOK, models.ClientTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(ttl.Seconds()),
+		Scope:       strings.Join(granted, " "),
+	})
+}
+
+// authenticateClient checks the secret against every live secret of an enabled client
+func authenticateClient(c echo.Context, db *sql.DB, clientID, secret string) (id, secretID int64, scopes []string, ok bool, err error) {
+	rows, err := db.QueryContext(c.Request().Context(), `
+		SELECT oc.id, oc.scopes, s.id, s.secret_hash
+		FROM oauth_clients oc
+		JOIN oauth_client_secrets s ON s.client_id = oc.id
+		WHERE oc.client_id = ? AND oc.disabled_at IS NULL
+		  AND (s.expires_at IS NULL OR s.expires_at > NOW())`, clientID)
+	if err != nil {
+		return 0, 0, nil, false, err
+	}
+	defer rows.Close()
+
+	hash := []byte(library.HashToken(secret))
+	for rows.Next() {
+		var (
+			candidateID, candidateSecret int64
+			candidateScopes, storedHash  string
+		)
+		if err := rows.Scan(&candidateID, &candidateScopes, &candidateSecret, &storedHash); err != nil {
+			return 0, 0, nil, false, err
+		}
+		if subtle.ConstantTimeCompare(hash, []byte(storedHash)) == 1 {
+			return candidateID, candidateSecret, strings.Fields(candidateScopes), true, nil
+		}
+	}
+	return 0, 0, nil, false, rows.Err()
+}
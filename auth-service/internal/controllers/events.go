@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"savannah-store/auth-service/internal/models"
+	"savannah-store/auth-service/internal/repository"
+	"savannah-store/pkg/mq"
+	"time"
+)
+
+// routing keys of the events published on the user.events exchange, see mq.Envelope
+const (
+	EventUserCreated     = "user.created"
+	EventUserVerified    = "user.verified"
+	EventUserRoleChanged = "user.role_changed"
+	EventUserDisabled    = "user.disabled"
+	EventUserEnabled     = "user.enabled"
+	EventUserDeleted     = "user.deleted"
+	EventUserLoginFailed = "user.login_failed"
+	EventUserLocked      = "user.locked"
+	EventUserErased      = "user.erased"
+	EventUserMFAReset    = "user.mfa_reset"
+	EventUserUnlocked    = "user.unlocked"
+)
+
+// the attributes a user.verified event is about
+const (
+	VerifiedEmail = "email"
+	VerifiedPhone = "phone"
+)
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// publishEvent is called once the change an event describes is committed, a failed publish is logged
+// and does not undo the change
+func publishEvent(pub *mq.Publisher, routing string, payload interface{}) {
+	if err := pub.Publish(routing, payload); err != nil {
+		log.Printf("failed to publish %s: %v", routing, err)
+	}
+}
+
+// publishUserCreated announces a new account, method is password or the identity provider it came from
+func publishUserCreated(ctx context.Context, db *sql.DB, pub *mq.Publisher, userID int64, method string) {
+	user, err := repository.NewUserRepository(db).FindByID(ctx, userID)
+	if err != nil {
+		log.Println("failed to load user for", EventUserCreated+":", err)
+		return
+	}
+	publishEvent(pub, EventUserCreated, models.UserCreatedEvent{
+		UserID:        user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Phone:         user.Phone,
+		FullName:      user.FullName,
+		Role:          user.Role,
+		Method:        method,
+		CreatedAt:     user.Created.UTC(),
+	})
+}
+
+// markVerified sets the email or phone of the user as verified and tells whether it was not before
+func markVerified(ctx context.Context, exec execer, userID int64, attribute string) (bool, error) {
+	column := "email_verified"
+	if attribute == VerifiedPhone {
+		column = "phone_verified"
+	}
+	res, err := exec.ExecContext(ctx, `UPDATE users SET `+column+` = 1 WHERE id = ? AND COALESCE(`+column+`, 0) = 0`, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// publishVerified announces an email or phone that markVerified newly verified
+func publishVerified(ctx context.Context, db *sql.DB, pub *mq.Publisher, userID int64, attribute string) {
+	user, err := repository.NewUserRepository(db).FindByID(ctx, userID)
+	if err != nil {
+		log.Println("failed to load user for", EventUserVerified+":", err)
+		return
+	}
+	publishEvent(pub, EventUserVerified, models.UserVerifiedEvent{
+		UserID:     user.ID,
+		Email:      user.Email,
+		Phone:      user.Phone,
+		Attribute:  attribute,
+		VerifiedAt: time.Now().UTC(),
+	})
+}
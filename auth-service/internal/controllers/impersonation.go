@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"savannah-store/auth-service/internal/library"
+	"savannah-store/auth-service/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const impersonationColumns = `SELECT id, admin_id, user_id, reason, started_at, expires_at, ended_at FROM impersonations`
+
+// StartImpersonation issues the admin a token acting as the user :id. The token carries the user's claims plus
+// an act claim naming the admin, has no refresh token and its sid is the impersonation id, so ending the
+// impersonation revokes it like a session. Only users whose permissions the admin holds can be impersonated.
+func StartImpersonation(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	ctx := c.Request().Context()
+	admin, ok := c.Get("claims").(*models.JwtCustomClaims)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"error": "unauthorized"})
+	}
+
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+	if userID == admin.UserID {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "you can not impersonate yourself"})
+	}
+
+	req := new(models.ImpersonationRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "reason is required"})
+	}
+
+	claims, err := userClaims(ctx, db, userID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "user not found"})
+	}
+	if errors.Is(err, ErrAccountDisabled) {
+		return c.JSON(http.StatusForbidden, echo.Map{"error": "the account is disabled"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	// the admin's current permissions, not the ones in the token, decide what they may act as
+	adminPerms, err := rolePermissions(ctx, db, admin.RoleID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to fetch permissions"})
+	}
+	held := map[string]bool{}
+	for _, p := range adminPerms {
+		held[p] = true
+	}
+	for _, p := range claims.Perms {
+		if !held[p] {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "you can not impersonate a user holding permission " + p})
+		}
+	}
+
+	id, err := library.RandomToken(16)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to generate token"})
+	}
+	jti, err := library.RandomToken(16)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to generate token"})
+	}
+
+	library.AwaitRevocationSecond(rdb, userID)
+	now := time.Now()
+	imp := models.Impersonation{
+		ID:        id,
+		AdminID:   admin.UserID,
+		UserID:    userID,
+		Reason:    req.Reason,
+		StartedAt: now.UTC(),
+		ExpiresAt: now.Add(library.ImpersonationTTL()).UTC(),
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO impersonations (id, admin_id, user_id, reason, started_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		imp.ID, imp.AdminID, imp.UserID, imp.Reason, imp.StartedAt, imp.ExpiresAt)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	claims.SessionID = id
+	claims.Act = &models.Actor{Sub: admin.Subject, UserID: admin.UserID, Email: admin.Email}
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        jti,
+		Subject:   strconv.FormatInt(userID, 10),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(imp.ExpiresAt),
+	}
+	imp.Token, err = library.SignToken(claims)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to sign token"})
+	}
+
+	recordImpersonation(ctx, db, c, imp, http.StatusCreated)
+	log.Printf("admin %d started impersonating user %d (%s): %s", admin.UserID, userID, id, req.Reason)
+	return c.JSON(http.StatusCreated, imp)
+}
+
+// EndImpersonation revokes the token of an impersonation before it expires
+func EndImpersonation(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	ctx := c.Request().Context()
+	imp, err := loadImpersonation(ctx, db, c.Param("id"))
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "impersonation not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	if err := library.RevokeSession(rdb, imp.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to revoke token"})
+	}
+	if imp.EndedAt == nil {
+		if _, err := db.ExecContext(ctx, `UPDATE impersonations SET ended_at = NOW() WHERE id = ? AND ended_at IS NULL`, imp.ID); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		recordImpersonation(ctx, db, c, *imp, http.StatusOK)
+	}
+
+	log.Printf("impersonation %s of user %d ended by admin %v", imp.ID, imp.UserID, c.Get("user_id"))
+	return c.JSON(http.StatusOK, echo.Map{"message": "impersonation ended"})
+}
+
+// ListImpersonations returns the latest impersonations, filtered by ?user_id, ?admin_id and ?active=true
+func ListImpersonations(c echo.Context, db *sql.DB) error {
+	var (
+		where []string
+		args  []interface{}
+	)
+	for _, param := range []string{"user_id", "admin_id"} {
+		if v := c.QueryParam(param); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid " + param})
+			}
+			where = append(where, param+" = ?")
+			args = append(args, id)
+		}
+	}
+	if c.QueryParam("active") == "true" {
+		where = append(where, "ended_at IS NULL AND expires_at > NOW()")
+	}
+	q := impersonationColumns
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	rows, err := db.QueryContext(c.Request().Context(), q+` ORDER BY started_at DESC LIMIT 100`, args...)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer rows.Close()
+
+	list := []models.Impersonation{}
+	for rows.Next() {
+		imp, err := scanImpersonation(rows)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		list = append(list, *imp)
+	}
+	return c.JSON(http.StatusOK, list)
+}
+
+// ImpersonationAudit returns every action recorded for the impersonation :id, oldest first
+func ImpersonationAudit(c echo.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(c.Request().Context(), `
+		SELECT impersonation_id, admin_id, user_id, service, method, path, status, COALESCE(ip, ''), created
+		FROM impersonation_audit
+		WHERE impersonation_id = ?
+		ORDER BY id`, c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer rows.Close()
+
+	entries := []models.ImpersonationAuditEntry{}
+	for rows.Next() {
+		var e models.ImpersonationAuditEntry
+		if err := rows.Scan(&e.ImpersonationID, &e.AdminID, &e.UserID, &e.Service, &e.Method, &e.Path, &e.Status, &e.IP, &e.Created); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		entries = append(entries, e)
+	}
+	return c.JSON(http.StatusOK, entries)
+}
+
+// RecordImpersonationAudit stores an action the catalog or order service served with an impersonation token
+func RecordImpersonationAudit(c echo.Context, db *sql.DB) error {
+	var e models.ImpersonationAuditEntry
+	if err := c.Bind(&e); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+	}
+	if e.ImpersonationID == "" || e.Service == "" || e.Method == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "impersonation_id, service and method are required"})
+	}
+	if err := insertAuditEntry(c.Request().Context(), db, e); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, echo.Map{"message": "recorded"})
+}
+
+// recordImpersonation audits starting or ending an impersonation, failures are only logged
+func recordImpersonation(ctx context.Context, db *sql.DB, c echo.Context, imp models.Impersonation, status int) {
+	err := insertAuditEntry(ctx, db, models.ImpersonationAuditEntry{
+		ImpersonationID: imp.ID,
+		AdminID:         imp.AdminID,
+		UserID:          imp.UserID,
+		Service:         "auth-service",
+		Method:          c.Request().Method,
+		Path:            c.Request().URL.RequestURI(),
+		Status:          status,
+		IP:              c.RealIP(),
+	})
+	if err != nil {
+		log.Println("failed to audit impersonation:", err)
+	}
+}
+
+func insertAuditEntry(ctx context.Context, db *sql.DB, e models.ImpersonationAuditEntry) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO impersonation_audit (impersonation_id, admin_id, user_id, service, method, path, status, ip)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''))`,
+		e.ImpersonationID, e.AdminID, e.UserID, e.Service, e.Method, e.Path, e.Status, e.IP)
+	return err
+}
+
+func loadImpersonation(ctx context.Context, db *sql.DB, id string) (*models.Impersonation, error) {
+	return scanImpersonation(db.QueryRowContext(ctx, impersonationColumns+` WHERE id = ?`, id))
+}
+
+func scanImpersonation(row interface{ Scan(...interface{}) error }) (*models.Impersonation, error) {
+	imp := &models.Impersonation{}
+	var endedAt sql.NullTime
+	if err := row.Scan(&imp.ID, &imp.AdminID, &imp.UserID, &imp.Reason, &imp.StartedAt, &imp.ExpiresAt, &endedAt); err != nil {
+		return nil, err
+	}
+	if endedAt.Valid {
+		imp.EndedAt = &endedAt.Time
+	}
+	return imp, nil
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"savannah-store/auth-service/internal/library"
+	"savannah-store/auth-service/internal/models"
+	"savannah-store/pkg/authn"
+	"strconv"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+)
+
+// IntrospectRequest carries the token to introspect, as JSON or form (RFC 7662)
+type IntrospectRequest struct {
+	Token string `json:"token" form:"token"`
+}
+
+const userProfileQuery = `
+	SELECT u.id, u.email, COALESCE(u.email_verified, 0), COALESCE(u.phone, ''), COALESCE(u.full_name, ''), COALESCE(r.name, '')
+	FROM users u
+	LEFT JOIN roles r ON r.id = u.role_id`
+
+// Introspect tells other services whether an access token is still active and what it grants
+func Introspect(c echo.Context, rdb *redis.Client) error {
+	var req IntrospectRequest
+	if err := c.Bind(&req); err != nil || req.Token == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "missing token"})
+	}
+
+	claims := &models.JwtCustomClaims{}
+	if err := library.ParseToken(req.Token, claims); err != nil {
+		return c.JSON(http.StatusOK, echo.Map{"active": false})
+	}
+
+	revoked, err := authn.IsRevoked(rdb, claims)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to check token status"})
+	}
+	if revoked {
+		return c.JSON(http.StatusOK, echo.Map{"active": false})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"active":    true,
+		"sub":       claims.Subject,
+		"user_id":   claims.UserID,
+		"client_id": claims.ClientID,
+		"email":     claims.Email,
+		"role":      claims.Role,
+		"perms":     claims.Perms,
+		"sid":       claims.SessionID,
+		"act":       claims.Act,
+		"jti":       claims.ID,
+		"iat":       claims.IssuedAt,
+		"exp":       claims.ExpiresAt,
+	})
+}
+
+// LookupUser returns the profile of a single user
+func LookupUser(c echo.Context, db *sql.DB) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+
+	var u models.UserProfile
+	err = db.QueryRowContext(c.Request().Context(), userProfileQuery+` WHERE u.id = ? AND u.deleted_at IS NULL`, id).
+		Scan(&u.ID, &u.Email, &u.EmailVerified, &u.Phone, &u.FullName, &u.Role)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "user not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, u)
+}
+
+// LookupUsersByRole returns the profiles of every user holding ?role=
+func LookupUsersByRole(c echo.Context, db *sql.DB) error {
+	role := c.QueryParam("role")
+	if role == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "role is required"})
+	}
+
+	rows, err := db.QueryContext(c.Request().Context(), userProfileQuery+` WHERE r.name = ? AND u.deleted_at IS NULL AND u.disabled_at IS NULL ORDER BY u.id`, role)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer rows.Close()
+
+	users := []models.UserProfile{}
+	for rows.Next() {
+		var u models.UserProfile
+		if err := rows.Scan(&u.ID, &u.Email, &u.EmailVerified, &u.Phone, &u.FullName, &u.Role); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		users = append(users, u)
+	}
+
+	return c.JSON(http.StatusOK, users)
+}
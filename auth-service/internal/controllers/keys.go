@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"savannah-store/auth-service/internal/library"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SigningKeyResponse describes a signing key without its private part
+type SigningKeyResponse struct {
+	Kid       string     `json:"kid"`
+	Algorithm string     `json:"algorithm"`
+	Created   time.Time  `json:"created"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+}
+
+// JWKS publishes the public signing keys, including retired keys still inside the overlap window
+func JWKS(c echo.Context) error {
+	keys, err := library.JWKS()
+	if err != nil {
+		log.Println("failed to load signing keys:", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to load signing keys"})
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, echo.Map{"keys": keys})
+}
+
+// ListSigningKeys returns the published signing keys
+func ListSigningKeys(c echo.Context) error {
+	keys, err := library.PublishedSigningKeys()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	resp := []SigningKeyResponse{}
+	for _, k := range keys {
+		resp = append(resp, SigningKeyResponse{Kid: k.Kid, Algorithm: k.Algorithm, Created: k.Created, RetiredAt: k.RetiredAt})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// RotateSigningKey creates a new signing key, the previous one keeps verifying for the overlap window
+func RotateSigningKey(c echo.Context) error {
+	key, err := library.RotateSigningKey()
+	if err != nil {
+		log.Println("failed to rotate signing key:", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to rotate signing key"})
+	}
+
+	return c.JSON(http.StatusCreated, SigningKeyResponse{Kid: key.Kid, Algorithm: key.Algorithm, Created: key.Created})
+}
@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"savannah-store/auth-service/internal/library"
+	"savannah-store/auth-service/internal/models"
+	"savannah-store/pkg/mq"
+	"savannah-store/pkg/redisx"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultLoginMaxAttempts      = 5
+	defaultLoginMaxAttemptsPerIP = 20
+	defaultLoginLockout          = 15 * time.Minute
+	// failures are forgotten after this long without a new one
+	loginFailureWindow = 15 * time.Minute
+	// failures before the progressive delay starts, it doubles from one second with each further failure
+	loginFreeAttempts = 2
+)
+
+// failures and locks are kept per account and per client IP; accounts that do not exist are counted by
+// the hashed identifier so they lock exactly like real ones and the lock reveals nothing
+func loginFailKey(subject string) string { return fmt.Sprintf("login:fail:%s", subject) }
+func loginLockKey(subject string) string { return fmt.Sprintf("login:lock:%s", subject) }
+
+func loginAccountSubject(userID int64, identifier string) string {
+	if userID != 0 {
+		return fmt.Sprintf("user:%d", userID)
+	}
+	return "id:" + library.HashToken(strings.ToLower(strings.TrimSpace(identifier)))
+}
+
+func loginIPSubject(ip string) string { return "ip:" + ip }
+
+func envInt(name string, fallback int) int {
+	n, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
+
+// loginLockout is how long an account or IP stays locked, LOGIN_LOCKOUT (seconds)
+func loginLockout() time.Duration {
+	return time.Duration(envInt("LOGIN_LOCKOUT", int(defaultLoginLockout.Seconds()))) * time.Second
+}
+
+// loginLocked returns how long the account or the IP still has to wait, zero when neither is locked
+func loginLocked(rdb *redis.Client, account, ip string) time.Duration {
+	var wait time.Duration
+	for _, subject := range []string{account, loginIPSubject(ip)} {
+		ttl, err := rdb.TTL(loginLockKey(subject)).Result()
+		if err == nil && ttl > wait {
+			wait = ttl
+		}
+	}
+	return wait
+}
+
+// tooManyAttempts answers a locked login, with the wait in Retry-After
+func tooManyAttempts(c echo.Context, wait time.Duration) error {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return c.JSON(http.StatusTooManyRequests, echo.Map{"error": "too many failed attempts, try again later", "retry_after": seconds})
+}
+
+// registerLoginFailure counts a failed login for the account and the IP. Past loginFreeAttempts every failure
+// locks the account for a doubling delay, at LOGIN_MAX_ATTEMPTS (LOGIN_MAX_ATTEMPTS_PER_IP for an IP) it is
+// locked for LOGIN_LOCKOUT. Every failure is published as user.login_failed, a lockout as user.locked.
+func registerLoginFailure(rdb *redis.Client, pub *mq.Publisher, userID int64, identifier, ip string) {
+	account := loginAccountSubject(userID, identifier)
+	now := time.Now().UTC()
+
+	accountFailures, err := redisx.Incr(rdb, loginFailKey(account))
+	if err != nil {
+		log.Println("failed to count login failure:", err)
+		return
+	}
+	rdb.Expire(loginFailKey(account), loginFailureWindow)
+
+	ipFailures, err := redisx.Incr(rdb, loginFailKey(loginIPSubject(ip)))
+	if err != nil {
+		log.Println("failed to count login failure:", err)
+		return
+	}
+	rdb.Expire(loginFailKey(loginIPSubject(ip)), loginFailureWindow)
+
+	event := models.LoginFailedEvent{
+		UserID:     userID,
+		Identifier: identifier,
+		IP:         ip,
+		Failures:   accountFailures,
+		IPFailures: ipFailures,
+		FailedAt:   now,
+	}
+	publishEvent(pub, EventUserLoginFailed, event)
+
+	if max := int64(envInt("LOGIN_MAX_ATTEMPTS", defaultLoginMaxAttempts)); accountFailures >= max {
+		lockLogin(rdb, pub, account, loginLockout(), models.UserLockedEvent{
+			UserID:     userID,
+			Identifier: identifier,
+			IP:         ip,
+			Failures:   accountFailures,
+			LockedAt:   now,
+			Until:      now.Add(loginLockout()),
+		})
+	} else if accountFailures > loginFreeAttempts {
+		delay := time.Duration(1<<uint(accountFailures-loginFreeAttempts-1)) * time.Second
+		rdb.Set(loginLockKey(account), now.Add(delay).Unix(), delay)
+	}
+
+	if max := int64(envInt("LOGIN_MAX_ATTEMPTS_PER_IP", defaultLoginMaxAttemptsPerIP)); ipFailures >= max {
+		lockLogin(rdb, pub, loginIPSubject(ip), loginLockout(), models.UserLockedEvent{
+			IP:       ip,
+			Failures: ipFailures,
+			LockedAt: now,
+			Until:    now.Add(loginLockout()),
+		})
+	}
+}
+
+func lockLogin(rdb *redis.Client, pub *mq.Publisher, subject string, lockout time.Duration, event models.UserLockedEvent) {
+	if err := rdb.Set(loginLockKey(subject), event.Until.Unix(), lockout).Err(); err != nil {
+		log.Println("failed to lock login:", err)
+		return
+	}
+	// the counter starts over once the lock expires
+	rdb.Del(loginFailKey(subject))
+
+	log.Printf("login locked for %s until %s after %d failures", subject, event.Until.Format(time.RFC3339), event.Failures)
+	publishEvent(pub, EventUserLocked, event)
+}
+
+// clearLoginFailures forgets the failures of an account after a successful login
+func clearLoginFailures(rdb *redis.Client, userID int64) {
+	rdb.Del(loginFailKey(loginAccountSubject(userID, "")))
+}
+
+// UnlockUser lifts the lock and failure count of the user :id, and of the client IP given as ?ip. It reopens
+// the account to password guessing, so it is published as user.unlocked for alerting.
+func UnlockUser(c echo.Context, rdb *redis.Client, pub *mq.Publisher) error {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+
+	subjects := []string{loginAccountSubject(userID, "")}
+	ip := c.QueryParam("ip")
+	if ip != "" {
+		subjects = append(subjects, loginIPSubject(ip))
+	}
+	for _, subject := range subjects {
+		if err := rdb.Del(loginLockKey(subject), loginFailKey(subject)).Err(); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+	}
+
+	adminID := c.Get("user_id").(int64)
+	log.Printf("login of user %d unlocked by admin %d", userID, adminID)
+	publishEvent(pub, EventUserUnlocked, models.UserUnlockedEvent{UserID: userID, IP: ip, UnlockedBy: adminID, UnlockedAt: time.Now().UTC()})
+	return c.JSON(http.StatusOK, echo.Map{"message": "unlocked"})
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"savannah-store/auth-service/internal/library"
+	"savannah-store/auth-service/internal/models"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+)
+
+// Logout revokes the calling access token and the refresh token family it belongs to
+func Logout(c echo.Context, rdb *redis.Client) error {
+	claims := c.Get("claims").(*models.JwtCustomClaims)
+
+	if claims.ExpiresAt != nil {
+		if err := library.RevokeToken(rdb, claims.ID, claims.ExpiresAt.Time); err != nil {
+			log.Println("failed to revoke token:", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to revoke token"})
+		}
+	}
+
+	if claims.SessionID != "" {
+		if err := revokeFamily(rdb, claims.UserID, claims.SessionID); err != nil {
+			log.Println("failed to revoke token family:", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to revoke session"})
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "logged out"})
+}
+
+// LogoutAll revokes every access and refresh token issued to the calling user
+func LogoutAll(c echo.Context, rdb *redis.Client) error {
+	userID := c.Get("user_id").(int64)
+
+	if err := RevokeUserSessions(rdb, userID); err != nil {
+		log.Println("failed to revoke sessions:", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to revoke sessions"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "all sessions logged out"})
+}
+
+// RevokeUserSessions logs the user out everywhere
+func RevokeUserSessions(rdb *redis.Client, userID int64) error {
+	if err := library.RevokeAllUserTokens(rdb, userID); err != nil {
+		return err
+	}
+	return revokeAllFamilies(rdb, userID)
+}
@@ -0,0 +1,452 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"savannah-store/auth-service/internal/library"
+	"savannah-store/auth-service/internal/models"
+	"savannah-store/pkg/mq"
+	"savannah-store/pkg/redisx"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	mfaMaxAttempts    = 5
+	recoveryCodeCount = 10
+)
+
+func mfaChallengeKey(hash string) string { return fmt.Sprintf("mfa:challenge:%s", hash) }
+func mfaAttemptsKey(hash string) string  { return fmt.Sprintf("mfa:attempts:%s", hash) }
+
+// SecondFactor runs after a successful first login step. It returns nil when tokens can be issued right
+// away, otherwise the challenge to answer instead: a TOTP code when 2FA is enabled, or an enrollment
+// when the policy makes 2FA mandatory for the user's role and it is not set up yet.
+func SecondFactor(ctx context.Context, db *sql.DB, rdb *redis.Client, userID int64) (echo.Map, error) {
+	enabled, err := mfaEnabled(ctx, db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		challenge, err := newMFAChallenge(rdb, models.MFAChallenge{UserID: userID})
+		if err != nil {
+			return nil, err
+		}
+		return echo.Map{"mfa_required": true, "challenge": challenge, "expires_in": int(mfaChallengeTTL.Seconds())}, nil
+	}
+
+	required, err := MFARequired(ctx, db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if required {
+		challenge, err := newMFAChallenge(rdb, models.MFAChallenge{UserID: userID, Enroll: true})
+		if err != nil {
+			return nil, err
+		}
+		return echo.Map{"mfa_enrollment_required": true, "challenge": challenge, "expires_in": int(mfaChallengeTTL.Seconds())}, nil
+	}
+
+	return nil, nil
+}
+
+// MFARequired applies the MFA_REQUIRED_FOR_WRITE policy: 2FA is mandatory for roles holding a create,
+// update or delete permission, except on the self service modules in MFA_EXEMPT_MODULES (default cart,order)
+func MFARequired(ctx context.Context, db *sql.DB, userID int64) (bool, error) {
+	if os.Getenv("MFA_REQUIRED_FOR_WRITE") != "true" {
+		return false, nil
+	}
+
+	exempt := os.Getenv("MFA_EXEMPT_MODULES")
+	if exempt == "" {
+		exempt = "cart,order"
+	}
+
+	query := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM users u
+			JOIN role_permissions rp ON rp.role_id = u.role_id
+			JOIN permissions p ON p.id = rp.permission_id
+			JOIN modules m ON m.id = p.module_id
+			WHERE u.id = ? AND p.action IN ('create', 'update', 'delete')`
+	args := []interface{}{userID}
+	for _, module := range strings.Split(exempt, ",") {
+		if module = strings.TrimSpace(module); module != "" {
+			query += ` AND m.name <> ?`
+			args = append(args, module)
+		}
+	}
+	query += `)`
+
+	var required bool
+	err := db.QueryRowContext(ctx, query, args...).Scan(&required)
+	return required, err
+}
+
+// VerifyMFA answers a login challenge with a TOTP or recovery code and issues the tokens
+func VerifyMFA(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	ctx := c.Request().Context()
+
+	req := new(models.MFACodeRequest)
+	if err := c.Bind(req); err != nil || req.Challenge == "" || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "challenge and code are required"})
+	}
+
+	challenge, httpErr := loadMFAChallenge(rdb, req.Challenge)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+	if challenge.Enroll {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "2FA has to be set up first"})
+	}
+
+	ok, err := verifyMFACode(ctx, db, challenge.UserID, req.Code, true)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid code"})
+	}
+
+	// the challenge is single use, a concurrent request with the same challenge loses
+	if _, err := redisx.Consume(rdb, mfaChallengeKey(library.HashToken(req.Challenge))); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid or expired challenge"})
+	}
+
+	tokens, err := IssueTokens(c, db, rdb, challenge.UserID)
+	if err != nil {
+		return tokenError(c, err)
+	}
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// EnrollMFA creates a new TOTP secret for the user and returns its provisioning URI. 2FA is only
+// switched on once ActivateMFA confirms a first code.
+func EnrollMFA(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	ctx := c.Request().Context()
+
+	req := new(models.MFAEnrollRequest)
+	_ = c.Bind(req)
+
+	userID, _, httpErr := mfaSubject(c, rdb, req.Challenge)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+
+	enabled, err := mfaEnabled(ctx, db, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if enabled {
+		return c.JSON(http.StatusConflict, echo.Map{"error": "2FA is already enabled"})
+	}
+
+	var email string
+	if err := db.QueryRowContext(ctx, `SELECT email FROM users WHERE id = ?`, userID).Scan(&email); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	secret, err := library.GenerateTOTPSecret()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to generate secret"})
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO user_mfa (user_id, secret) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE secret = VALUES(secret), enabled_at = NULL, last_used_step = 0`, userID, secret)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, models.MFAEnrollment{
+		Secret:     secret,
+		OtpauthURL: library.TOTPProvisioningURI(email, secret),
+	})
+}
+
+// ActivateMFA confirms the enrollment with a first TOTP code and returns the recovery codes, once.
+// When enrolling during login the tokens are returned as well.
+func ActivateMFA(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	ctx := c.Request().Context()
+
+	req := new(models.MFACodeRequest)
+	if err := c.Bind(req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "code is required"})
+	}
+
+	userID, duringLogin, httpErr := mfaSubject(c, rdb, req.Challenge)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+
+	var (
+		secret    string
+		enabledAt sql.NullTime
+	)
+	err := db.QueryRowContext(ctx, `SELECT secret, enabled_at FROM user_mfa WHERE user_id = ?`, userID).Scan(&secret, &enabledAt)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "enroll first"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if enabledAt.Valid {
+		return c.JSON(http.StatusConflict, echo.Map{"error": "2FA is already enabled"})
+	}
+
+	step, ok := library.ValidateTOTP(secret, req.Code, time.Now())
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid code"})
+	}
+	if _, err := db.ExecContext(ctx, `UPDATE user_mfa SET enabled_at = NOW(), last_used_step = ? WHERE user_id = ?`, step, userID); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, db, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	response := echo.Map{"message": "2FA enabled, store the recovery codes somewhere safe", "recovery_codes": codes}
+
+	if duringLogin {
+		if _, err := redisx.Consume(rdb, mfaChallengeKey(library.HashToken(req.Challenge))); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid or expired challenge"})
+		}
+		tokens, err := IssueTokens(c, db, rdb, userID)
+		if err != nil {
+			return tokenError(c, err)
+		}
+		response["token"] = tokens.Token
+		response["expires_at"] = tokens.ExpiresAt
+		response["refresh_token"] = tokens.RefreshToken
+		response["refresh_expires_at"] = tokens.RefreshExpiresAt
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// DisableMFA switches 2FA off for the calling user after checking a TOTP or recovery code
+func DisableMFA(c echo.Context, db *sql.DB) error {
+	ctx := c.Request().Context()
+	userID := c.Get("user_id").(int64)
+
+	req := new(models.MFACodeRequest)
+	if err := c.Bind(req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "code is required"})
+	}
+
+	required, err := MFARequired(ctx, db, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if required {
+		return c.JSON(http.StatusForbidden, echo.Map{"error": "2FA is mandatory for your role"})
+	}
+
+	ok, err := verifyMFACode(ctx, db, userID, req.Code, true)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid code"})
+	}
+
+	if err := removeMFA(ctx, db, userID); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": "2FA disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the calling user after checking a TOTP code
+func RegenerateRecoveryCodes(c echo.Context, db *sql.DB) error {
+	ctx := c.Request().Context()
+	userID := c.Get("user_id").(int64)
+
+	req := new(models.MFACodeRequest)
+	if err := c.Bind(req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "code is required"})
+	}
+
+	ok, err := verifyMFACode(ctx, db, userID, req.Code, false)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid code"})
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, db, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"recovery_codes": codes})
+}
+
+// ResetUserMFA removes the 2FA of a user who lost both the device and the recovery codes. It weakens the
+// account, so it is published as user.mfa_reset for alerting.
+func ResetUserMFA(c echo.Context, db *sql.DB, pub *mq.Publisher) error {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+
+	if err := removeMFA(c.Request().Context(), db, userID); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	adminID := c.Get("user_id").(int64)
+	log.Printf("2FA of user %d reset by admin %d", userID, adminID)
+	publishEvent(pub, EventUserMFAReset, models.UserMFAResetEvent{UserID: userID, ResetBy: adminID, ResetAt: time.Now().UTC()})
+	return c.JSON(http.StatusOK, echo.Map{"message": "2FA reset"})
+}
+
+func mfaEnabled(ctx context.Context, db *sql.DB, userID int64) (bool, error) {
+	var enabled bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM user_mfa WHERE user_id = ? AND enabled_at IS NOT NULL)`, userID).Scan(&enabled)
+	return enabled, err
+}
+
+// verifyMFACode checks a TOTP code, refusing a time step that was already used, or when allowed a recovery code
+func verifyMFACode(ctx context.Context, db *sql.DB, userID int64, code string, allowRecovery bool) (bool, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+
+	if _, err := strconv.Atoi(code); err != nil {
+		if !allowRecovery {
+			return false, nil
+		}
+		res, err := db.ExecContext(ctx, `UPDATE mfa_recovery_codes SET used_at = NOW() WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`,
+			userID, library.HashToken(code))
+		if err != nil {
+			return false, err
+		}
+		n, _ := res.RowsAffected()
+		return n == 1, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var (
+		secret   string
+		lastStep int64
+	)
+	err = tx.QueryRowContext(ctx, `SELECT secret, last_used_step FROM user_mfa WHERE user_id = ? AND enabled_at IS NOT NULL FOR UPDATE`, userID).Scan(&secret, &lastStep)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := library.ValidateTOTP(secret, code, time.Now())
+	if !ok || step <= lastStep {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE user_mfa SET last_used_step = ? WHERE user_id = ?`, step, userID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, db *sql.DB, userID int64) ([]string, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := library.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, library.HashToken(code)); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, tx.Commit()
+}
+
+func removeMFA(ctx context.Context, db *sql.DB, userID int64) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = ?`, userID)
+	return err
+}
+
+func newMFAChallenge(rdb *redis.Client, challenge models.MFAChallenge) (string, error) {
+	token, err := library.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	data, _ := json.Marshal(challenge)
+	if err := redisx.SetWithExpiry(rdb, mfaChallengeKey(library.HashToken(token)), string(data), int(mfaChallengeTTL.Seconds())); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// loadMFAChallenge reads a challenge and counts the attempt, a challenge is dropped after mfaMaxAttempts
+func loadMFAChallenge(rdb *redis.Client, token string) (*models.MFAChallenge, *echo.HTTPError) {
+	hash := library.HashToken(token)
+
+	data, err := rdb.Get(mfaChallengeKey(hash)).Result()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid or expired challenge")
+	}
+	var challenge models.MFAChallenge
+	if err := json.Unmarshal([]byte(data), &challenge); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid or expired challenge")
+	}
+
+	attempts, err := rdb.Incr(mfaAttemptsKey(hash)).Result()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to count attempts")
+	}
+	rdb.Expire(mfaAttemptsKey(hash), mfaChallengeTTL)
+	if attempts > mfaMaxAttempts {
+		rdb.Del(mfaChallengeKey(hash))
+		return nil, echo.NewHTTPError(http.StatusTooManyRequests, "too many attempts, sign in again")
+	}
+
+	return &challenge, nil
+}
+
+// mfaSubject is the logged in user, or during login the user of an enrollment challenge
+func mfaSubject(c echo.Context, rdb *redis.Client, challengeToken string) (int64, bool, *echo.HTTPError) {
+	if userID, ok := c.Get("user_id").(int64); ok {
+		return userID, false, nil
+	}
+	if challengeToken == "" {
+		return 0, false, echo.NewHTTPError(http.StatusUnauthorized, "missing api-key header or challenge")
+	}
+
+	challenge, httpErr := loadMFAChallenge(rdb, challengeToken)
+	if httpErr != nil {
+		return 0, false, httpErr
+	}
+	if !challenge.Enroll {
+		return 0, false, echo.NewHTTPError(http.StatusBadRequest, "challenge is not an enrollment")
+	}
+	return challenge.UserID, true, nil
+}
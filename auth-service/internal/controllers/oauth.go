@@ -0,0 +1,349 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"savannah-store/auth-service/internal/library"
+	"savannah-store/auth-service/internal/models"
+	"savannah-store/pkg/mq"
+	"savannah-store/pkg/redisx"
+	"strconv"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/oauth2"
+)
+
+func oauthStateKey(state string) string { return fmt.Sprintf("oauth:state:%s", state) }
+
+// oauthLinkCookie ties a link flow to the browser that started it. It holds the hash of the state, so a link
+// started by someone else can not be completed by sending its provider redirect to a victim.
+const oauthLinkCookie = "oauth_link"
+
+func setOAuthLinkCookie(c echo.Context, value string, maxAge int) {
+	c.SetCookie(&http.Cookie{
+		Name:     oauthLinkCookie,
+		Value:    value,
+		Path:     "/auth",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		// the provider redirect is a top level cross site navigation, Strict would drop the cookie on it
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// StartProviderAuth stores a single use state (with the PKCE verifier and nonce) and returns the provider
+// login URL. When called with a valid api-key the identity is linked to that user instead of logging in, and
+// the callback then has to come from the same browser.
+func StartProviderAuth(c echo.Context, rdb *redis.Client, provider *library.Provider) error {
+	var req models.AuthRequest
+	_ = c.Bind(&req) // ignore error if empty
+
+	state, err := library.RandomToken(32)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to generate state"})
+	}
+	nonce, err := library.RandomToken(16)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to generate nonce"})
+	}
+	oauthState := models.OAuthState{
+		Provider:     provider.Name,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		Nonce:        nonce,
+		Phone:        req.Phone,
+		Invite:       req.Invite,
+	}
+	if userID, ok := c.Get("user_id").(int64); ok {
+		oauthState.LinkUserID = userID
+	}
+
+	authURL, err := provider.AuthCodeURL(state, oauthState.CodeVerifier, nonce)
+	if err != nil {
+		log.Printf("provider %s is unavailable: %v", provider.Name, err)
+		return c.JSON(http.StatusBadGateway, echo.Map{"error": "identity provider is unavailable"})
+	}
+
+	stateData, _ := json.Marshal(oauthState)
+	if err := redisx.SetWithExpiry(rdb, oauthStateKey(state), string(stateData), 600); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to store state"})
+	}
+	if oauthState.LinkUserID != 0 {
+		setOAuthLinkCookie(c, library.HashToken(state), 600)
+	}
+
+	response := echo.Map{
+		"auth_url": authURL,
+		"state":    state,
+	}
+	if provider.Name == library.ProviderGoogle {
+		response["google_url"] = authURL // kept for clients of the former Google only flow
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// ProviderCallback completes the provider login: it signs in the user owning the identity, links it to an
+// existing account with the same verified email or creates a customer account, and issues tokens
+func ProviderCallback(c echo.Context, db *sql.DB, rdb *redis.Client, pub *mq.Publisher, provider *library.Provider) error {
+	ctx := c.Request().Context()
+
+	code := c.QueryParam("code")
+	state := c.QueryParam("state")
+	if code == "" || state == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "missing code or state"})
+	}
+
+	// The state must have been issued by StartProviderAuth for this provider and is consumed exactly once
+	stateData, err := redisx.Consume(rdb, oauthStateKey(state))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid or expired state"})
+	}
+	var oauthState models.OAuthState
+	if err := json.Unmarshal([]byte(stateData), &oauthState); err != nil || oauthState.Provider != provider.Name {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid or expired state"})
+	}
+
+	token, err := provider.Exchange(ctx, code, oauthState.CodeVerifier)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to exchange token"})
+	}
+
+	identity, err := provider.Identity(ctx, token, oauthState.Nonce)
+	if err != nil {
+		log.Printf("%s login rejected: %v", provider.Name, err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid identity"})
+	}
+
+	if oauthState.LinkUserID != 0 {
+		cookie, err := c.Cookie(oauthLinkCookie)
+		if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(library.HashToken(state))) != 1 {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "the link must be completed in the browser that started it"})
+		}
+		setOAuthLinkCookie(c, "", -1)
+		if httpErr := linkIdentity(ctx, db, oauthState.LinkUserID, identity); httpErr != nil {
+			return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+		}
+		return c.JSON(http.StatusOK, echo.Map{"message": "identity linked", "provider": identity.Provider})
+	}
+
+	userID, httpErr := resolveIdentity(ctx, db, pub, identity, oauthState.Phone)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+
+	// An invite only elevates the account it was issued for, a bad invite does not block the login
+	inviteError := ""
+	if oauthState.Invite != "" {
+		if _, httpErr := RedeemInvite(ctx, db, pub, userID, oauthState.Invite); httpErr != nil {
+			inviteError = fmt.Sprint(httpErr.Message)
+		}
+	}
+
+	var (
+		user   models.UserProfile
+		roleID int64
+	)
+	err = db.QueryRowContext(ctx, `
+		SELECT u.id, u.email, COALESCE(u.full_name, ''), COALESCE(u.phone, ''), COALESCE(u.role_id, 0), COALESCE(r.name, '')
+		FROM users u
+		LEFT JOIN roles r ON r.id = u.role_id
+		WHERE u.id = ?`, userID,
+	).Scan(&user.ID, &user.Email, &user.FullName, &user.Phone, &roleID, &user.Role)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to load user"})
+	}
+
+	response := echo.Map{
+		"user": echo.Map{
+			"id":       user.ID,
+			"email":    user.Email,
+			"name":     user.FullName,
+			"phone":    user.Phone,
+			"role_id":  roleID,
+			"role":     user.Role,
+			"provider": identity.Provider,
+		},
+	}
+
+	// accounts with 2FA get a challenge instead of tokens
+	challenge, err := SecondFactor(ctx, db, rdb, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to check 2FA"})
+	}
+	if challenge != nil {
+		for k, v := range challenge {
+			response[k] = v
+		}
+	} else {
+		tokens, err := IssueTokens(c, db, rdb, userID)
+		if err != nil {
+			return tokenError(c, err)
+		}
+		response["message"] = "authenticated"
+		response["token"] = tokens.Token
+		response["expires_at"] = tokens.ExpiresAt
+		response["refresh_token"] = tokens.RefreshToken
+		response["refresh_expires_at"] = tokens.RefreshExpiresAt
+	}
+	if inviteError != "" {
+		response["invite_error"] = inviteError
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// resolveIdentity finds the user behind an external identity, linking or creating the account on first use.
+// Linking by email only happens when the provider vouches for the address, otherwise anyone able to
+// register that email with some provider could take the account over, and when the account verified it too,
+// otherwise whoever signed up with the address first would keep their password on the linked account.
+func resolveIdentity(ctx context.Context, db *sql.DB, pub *mq.Publisher, identity *models.ExternalIdentity, phone string) (int64, *echo.HTTPError) {
+	var userID int64
+	err := db.QueryRowContext(ctx, `SELECT user_id FROM user_identities WHERE provider = ? AND subject = ?`, identity.Provider, identity.Subject).Scan(&userID)
+	if err == nil {
+		if _, err := db.ExecContext(ctx, `UPDATE user_identities SET email = ?, last_login = NOW() WHERE provider = ? AND subject = ?`,
+			identity.Email, identity.Provider, identity.Subject); err != nil {
+			log.Println("failed to update identity:", err)
+		}
+		return userID, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, echo.NewHTTPError(http.StatusInternalServerError, "failed to check identity")
+	}
+
+	if identity.Email == "" {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "the identity provider did not share an email address")
+	}
+
+	var emailVerified bool
+	err = db.QueryRowContext(ctx, `SELECT id, COALESCE(email_verified, 0) FROM users WHERE email = ?`, identity.Email).Scan(&userID, &emailVerified)
+	switch {
+	case err == nil:
+		if !identity.EmailVerified || !emailVerified {
+			return 0, echo.NewHTTPError(http.StatusConflict, "an account with this email exists, sign in to it and link this provider")
+		}
+	case err == sql.ErrNoRows:
+		// new accounts are always customers
+		roleID, err := CustomerRoleID(ctx, db)
+		if err != nil {
+			return 0, echo.NewHTTPError(http.StatusInternalServerError, "customer role is missing")
+		}
+		res, err := db.ExecContext(ctx, `
+			INSERT INTO users (email, email_verified, full_name, phone, role_id)
+			VALUES (?, ?, ?, ?, ?)`,
+			identity.Email, identity.EmailVerified, identity.Name, phone, roleID,
+		)
+		if err != nil {
+			return 0, echo.NewHTTPError(http.StatusInternalServerError, "failed to create user")
+		}
+		userID, _ = res.LastInsertId()
+		publishUserCreated(ctx, db, pub, userID, identity.Provider)
+	default:
+		return 0, echo.NewHTTPError(http.StatusInternalServerError, "failed to check user")
+	}
+
+	if httpErr := linkIdentity(ctx, db, userID, identity); httpErr != nil {
+		return 0, httpErr
+	}
+	return userID, nil
+}
+
+// linkIdentity attaches an identity to a user, an identity belongs to one user only
+func linkIdentity(ctx context.Context, db *sql.DB, userID int64, identity *models.ExternalIdentity) *echo.HTTPError {
+	var owner int64
+	err := db.QueryRowContext(ctx, `SELECT user_id FROM user_identities WHERE provider = ? AND subject = ?`, identity.Provider, identity.Subject).Scan(&owner)
+	if err == nil {
+		if owner != userID {
+			return echo.NewHTTPError(http.StatusConflict, "this identity is linked to another account")
+		}
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to check identity")
+	}
+
+	_, err = db.ExecContext(ctx, `INSERT INTO user_identities (user_id, provider, subject, email, last_login) VALUES (?, ?, ?, ?, NOW())`,
+		userID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to link identity")
+	}
+	return nil
+}
+
+// ListIdentities returns the provider identities linked to the calling user
+func ListIdentities(c echo.Context, db *sql.DB) error {
+	identities, err := userIdentities(c.Request().Context(), db, c.Get("user_id").(int64))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, identities)
+}
+
+func userIdentities(ctx context.Context, db *sql.DB, userID int64) ([]models.UserIdentity, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, provider, subject, COALESCE(email, ''), created, last_login
+		FROM user_identities
+		WHERE user_id = ?
+		ORDER BY created`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []models.UserIdentity{}
+	for rows.Next() {
+		var (
+			identity  models.UserIdentity
+			lastLogin sql.NullTime
+		)
+		if err := rows.Scan(&identity.ID, &identity.Provider, &identity.Subject, &identity.Email, &identity.Created, &lastLogin); err != nil {
+			return nil, err
+		}
+		if lastLogin.Valid {
+			identity.LastLogin = &lastLogin.Time
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// UnlinkIdentity removes one of the calling user's identities, as long as the user can still sign in afterwards
+func UnlinkIdentity(c echo.Context, db *sql.DB) error {
+	ctx := c.Request().Context()
+	userID := c.Get("user_id").(int64)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid identity id"})
+	}
+
+	var (
+		hasPassword bool
+		identities  int
+	)
+	err = db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT password_hash IS NOT NULL FROM users WHERE id = ?),
+			(SELECT COUNT(*) FROM user_identities WHERE user_id = ?)`, userID, userID,
+	).Scan(&hasPassword, &identities)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if !hasPassword && identities <= 1 {
+		return c.JSON(http.StatusConflict, echo.Map{"error": "can not remove your only way to sign in"})
+	}
+
+	res, err := db.ExecContext(ctx, `DELETE FROM user_identities WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "identity not found"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "identity unlinked"})
+}
@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"savannah-store/auth-service/internal/library"
+	"savannah-store/auth-service/internal/models"
+	"savannah-store/pkg/mq"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// what a one time code was issued for, stored in users.otp_purpose
+const (
+	OTPPurposeLogin  = "login"
+	OTPPurposeVerify = "verify"
+)
+
+// where a one time code was sent, stored in users.otp_channel; a correct code proves control of it
+const (
+	OTPChannelEmail = "email"
+	OTPChannelSMS   = "sms"
+)
+
+// a new code can only be sent this often to the same user
+const otpResendInterval = time.Minute
+
+var errOTPThrottled = errors.New("a code was sent recently, wait before asking for a new one")
+
+func otpThrottleKey(userID int64) string { return fmt.Sprintf("otp:sent:%d", userID) }
+
+// RequestLoginOTP sends a one time login code to the email or phone of an account. The response is the
+// same whether or not the account exists, so it can not be used to find out who is registered.
+func RequestLoginOTP(c echo.Context, db *sql.DB, rdb *redis.Client, mq *amqp.Connection) error {
+	ctx := c.Request().Context()
+
+	req := new(models.OTPRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+	}
+
+	userID, channel, to, err := findOTPRecipient(ctx, db, req.Email, req.Phone)
+	if err != nil && err != sql.ErrNoRows {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+	if err == nil {
+		if err := issueOTP(ctx, db, rdb, mq, userID, OTPPurposeLogin, channel, to); err != nil && err != errOTPThrottled {
+			log.Println("failed to send login code:", err)
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to send code"})
+		}
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "if the account exists a code has been sent"})
+}
+
+// LoginWithOTP exchanges a one time login code for tokens. The code also proves the email or phone it was sent to.
+func LoginWithOTP(c echo.Context, db *sql.DB, rdb *redis.Client, pub *mq.Publisher) error {
+	ctx := c.Request().Context()
+
+	req := new(models.OTPLoginRequest)
+	if err := c.Bind(req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "code is required"})
+	}
+
+	userID, _, _, err := findOTPRecipient(ctx, db, req.Email, req.Phone)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid or expired code"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	verified, httpErr := checkOTP(ctx, db, userID, OTPPurposeLogin, req.Code)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+	if verified != "" {
+		publishVerified(ctx, db, pub, userID, verified)
+	}
+
+	challenge, err := SecondFactor(ctx, db, rdb, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if challenge != nil {
+		return c.JSON(http.StatusOK, challenge)
+	}
+
+	tokens, err := IssueTokens(c, db, rdb, userID)
+	if err != nil {
+		return tokenError(c, err)
+	}
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// RequestVerification sends a code to the email ("email") or phone ("sms") of the calling user
+func RequestVerification(c echo.Context, db *sql.DB, rdb *redis.Client, mq *amqp.Connection) error {
+	ctx := c.Request().Context()
+	userID := c.Get("user_id").(int64)
+
+	req := new(models.VerificationRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+	}
+	if req.Channel == "" {
+		req.Channel = OTPChannelEmail
+	}
+
+	var (
+		email, phone                 string
+		emailVerified, phoneVerified bool
+	)
+	err := db.QueryRowContext(ctx, `SELECT email, COALESCE(phone, ''), COALESCE(email_verified, 0), COALESCE(phone_verified, 0) FROM users WHERE id = ?`, userID).
+		Scan(&email, &phone, &emailVerified, &phoneVerified)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	var to string
+	switch req.Channel {
+	case OTPChannelEmail:
+		if emailVerified {
+			return c.JSON(http.StatusConflict, echo.Map{"error": "email already verified"})
+		}
+		to = email
+	case OTPChannelSMS:
+		if phone == "" {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "no phone number on the account"})
+		}
+		if phoneVerified {
+			return c.JSON(http.StatusConflict, echo.Map{"error": "phone already verified"})
+		}
+		to = phone
+	default:
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "channel must be email or sms"})
+	}
+
+	err = issueOTP(ctx, db, rdb, mq, userID, OTPPurposeVerify, req.Channel, to)
+	if err == errOTPThrottled {
+		return c.JSON(http.StatusTooManyRequests, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		log.Println("failed to send verification code:", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to send code"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "code sent", "channel": req.Channel})
+}
+
+// ConfirmVerification checks the code of the calling user and issues tokens for the verified account
+func ConfirmVerification(c echo.Context, db *sql.DB, rdb *redis.Client, pub *mq.Publisher) error {
+	ctx := c.Request().Context()
+	userID := c.Get("user_id").(int64)
+
+	req := new(models.VerificationConfirmRequest)
+	if err := c.Bind(req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "code is required"})
+	}
+
+	verified, httpErr := checkOTP(ctx, db, userID, OTPPurposeVerify, req.Code)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+	if verified != "" {
+		publishVerified(ctx, db, pub, userID, verified)
+	}
+
+	tokens, err := IssueTokens(c, db, rdb, userID)
+	if err != nil {
+		return tokenError(c, err)
+	}
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// SendEmailVerification sends the verification code for a freshly created account
+func SendEmailVerification(ctx context.Context, db *sql.DB, rdb *redis.Client, mq *amqp.Connection, userID int64, email string) error {
+	return issueOTP(ctx, db, rdb, mq, userID, OTPPurposeVerify, OTPChannelEmail, email)
+}
+
+// findOTPRecipient resolves the account and channel of a login code request
+func findOTPRecipient(ctx context.Context, db *sql.DB, email, phone string) (int64, string, string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	phone = strings.TrimSpace(phone)
+
+	switch {
+	case email != "":
+		var userID int64
+		err := db.QueryRowContext(ctx, `SELECT id FROM users WHERE email = ?`, email).Scan(&userID)
+		return userID, OTPChannelEmail, email, err
+	case phone != "":
+		userID, err := findUserByPhone(ctx, db, phone)
+		return userID, OTPChannelSMS, phone, err
+	}
+	return 0, "", "", errors.New("email or phone is required")
+}
+
+// findUserByPhone returns the only user with phone. Phone numbers are not unique, an ambiguous number
+// identifies nobody and yields sql.ErrNoRows like an unknown one.
+func findUserByPhone(ctx context.Context, db *sql.DB, phone string) (int64, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id FROM users WHERE phone = ? LIMIT 2`, phone)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) != 1 {
+		return 0, sql.ErrNoRows
+	}
+	return ids[0], nil
+}
+
+// issueOTP stores the hash of a new code on the user, replacing any previous one, and delivers it
+func issueOTP(ctx context.Context, db *sql.DB, rdb *redis.Client, mq *amqp.Connection, userID int64, purpose, channel, to string) (err error) {
+	sent, err := rdb.SetNX(otpThrottleKey(userID), 1, otpResendInterval).Result()
+	if err != nil {
+		return err
+	}
+	if !sent {
+		return errOTPThrottled
+	}
+	// a code that never went out should not hold back the next request
+	defer func() {
+		if err != nil {
+			rdb.Del(otpThrottleKey(userID))
+		}
+	}()
+
+	code, err := library.GenerateOTP()
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	ttl := library.OTPTTL()
+	_, err = db.ExecContext(ctx, `
+		UPDATE users SET otp = ?, otp_purpose = ?, otp_channel = ?, otp_expires_at = ?, otp_attempts = 0
+		WHERE id = ?`, string(hash), purpose, channel, time.Now().UTC().Add(ttl), userID)
+	if err != nil {
+		return err
+	}
+
+	notification := models.Notification{
+		Type:    channel,
+		To:      to,
+		Subject: "Your Savannah Store code",
+		Message: fmt.Sprintf("Your Savannah Store code is %s. It expires in %d minutes.", code, int(ttl.Minutes())),
+	}
+	return library.Notification(mq, notification)
+}
+
+// checkOTP consumes the user's code for purpose and marks the email or phone it was sent to as verified,
+// returning that attribute when it was not verified before.
+// Every wrong guess counts, once OTP_MAX_ATTEMPTS is reached the code is burnt.
+func checkOTP(ctx context.Context, db *sql.DB, userID int64, purpose, code string) (string, *echo.HTTPError) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer tx.Rollback()
+
+	var (
+		hash, storedPurpose, channel sql.NullString
+		expiresAt                    sql.NullTime
+		attempts                     int
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT otp, otp_purpose, otp_channel, otp_expires_at, otp_attempts
+		FROM users WHERE id = ? FOR UPDATE`, userID,
+	).Scan(&hash, &storedPurpose, &channel, &expiresAt, &attempts)
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "invalid or expired code")
+	}
+	if !hash.Valid || storedPurpose.String != purpose || !expiresAt.Valid || time.Now().After(expiresAt.Time) {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "invalid or expired code")
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash.String), []byte(code)) != nil {
+		attempts++
+		query := `UPDATE users SET otp_attempts = ? WHERE id = ?`
+		if attempts >= library.OTPMaxAttempts() {
+			query = `UPDATE users SET otp_attempts = ?, otp = NULL, otp_purpose = NULL, otp_channel = NULL, otp_expires_at = NULL WHERE id = ?`
+		}
+		if _, err := tx.ExecContext(ctx, query, attempts, userID); err != nil {
+			return "", echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if err := tx.Commit(); err != nil {
+			return "", echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if attempts >= library.OTPMaxAttempts() {
+			return "", echo.NewHTTPError(http.StatusTooManyRequests, "too many wrong codes, ask for a new one")
+		}
+		return "", echo.NewHTTPError(http.StatusBadRequest, "invalid or expired code")
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users SET otp = NULL, otp_purpose = NULL, otp_channel = NULL, otp_expires_at = NULL, otp_attempts = 0
+		WHERE id = ?`, userID)
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	attribute := VerifiedEmail
+	if channel.String == OTPChannelSMS {
+		attribute = VerifiedPhone
+	}
+	newlyVerified, err := markVerified(ctx, tx, userID, attribute)
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		return "", echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if !newlyVerified {
+		return "", nil
+	}
+	return attribute, nil
+}
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"savannah-store/auth-service/internal/library"
+	"savannah-store/auth-service/internal/models"
+	"savannah-store/pkg/mq"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// a reset link can only be sent this often to the same user
+const passwordResetResendInterval = time.Minute
+
+func passwordResetThrottleKey(userID int64) string {
+	return fmt.Sprintf("password:reset:sent:%d", userID)
+}
+
+// ForgotPassword emails a single use reset link. The response is the same whether or not the account exists.
+func ForgotPassword(c echo.Context, db *sql.DB, rdb *redis.Client, mq *amqp.Connection) error {
+	ctx := c.Request().Context()
+	generic := echo.Map{"message": "if the account exists a reset link has been sent"}
+
+	req := new(models.ForgotPasswordRequest)
+	if err := c.Bind(req); err != nil || strings.TrimSpace(req.Email) == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "email is required"})
+	}
+
+	var (
+		userID int64
+		email  string
+	)
+	err := db.QueryRowContext(ctx, `SELECT id, email FROM users WHERE email = ?`, strings.TrimSpace(req.Email)).Scan(&userID, &email)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusOK, generic)
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "server error"})
+	}
+
+	if sent, err := rdb.SetNX(passwordResetThrottleKey(userID), 1, passwordResetResendInterval).Result(); err != nil || !sent {
+		return c.JSON(http.StatusOK, generic)
+	}
+
+	token, err := library.RandomToken(32)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to generate token"})
+	}
+	ttl := library.PasswordResetTTL()
+
+	// only the newest link works
+	if _, err := db.ExecContext(ctx, `UPDATE password_resets SET used_at = NOW() WHERE user_id = ? AND used_at IS NULL`, userID); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES (?, ?, ?)`,
+		userID, library.HashToken(token), time.Now().UTC().Add(ttl))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	// PASSWORD_RESET_URL is the page that asks for the new password, e.g. https://shop.example.com/reset-password
+	link := token
+	if base := os.Getenv("PASSWORD_RESET_URL"); base != "" {
+		link = base + "?token=" + url.QueryEscape(token)
+	}
+	notification := models.Notification{
+		Type:    OTPChannelEmail,
+		To:      email,
+		Subject: "Reset your Savannah Store password",
+		Message: fmt.Sprintf("Use this link to choose a new password, it expires in %d minutes:\n\n%s\n\nIf you did not ask for this, ignore this email.", int(ttl.Minutes()), link),
+	}
+	if err := library.Notification(mq, notification); err != nil {
+		rdb.Del(passwordResetThrottleKey(userID))
+		log.Println("failed to send password reset:", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to send reset link"})
+	}
+
+	return c.JSON(http.StatusOK, generic)
+}
+
+// ResetPassword sets a new password with a reset token and logs the user out everywhere
+func ResetPassword(c echo.Context, db *sql.DB, rdb *redis.Client, mq *amqp.Connection, pub *mq.Publisher) error {
+	ctx := c.Request().Context()
+
+	req := new(models.ResetPasswordRequest)
+	if err := c.Bind(req); err != nil || req.Token == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "token is required"})
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer tx.Rollback()
+
+	var (
+		resetID, userID int64
+		email           string
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT pr.id, u.id, u.email
+		FROM password_resets pr
+		JOIN users u ON u.id = pr.user_id
+		WHERE pr.token_hash = ? AND pr.used_at IS NULL AND pr.expires_at > NOW()
+		FOR UPDATE`, library.HashToken(req.Token),
+	).Scan(&resetID, &userID, &email)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid or expired token"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	if err := library.CheckPasswordStrength(req.Password, email); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to hash password"})
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE password_resets SET used_at = NOW() WHERE id = ?`, resetID); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET password_hash = ? WHERE id = ?`, string(hash), userID); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	// the link was delivered to the mailbox, so the address is proven as well
+	newlyVerified, err := markVerified(ctx, tx, userID, VerifiedEmail)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if newlyVerified {
+		publishVerified(ctx, db, pub, userID, VerifiedEmail)
+	}
+
+	if err := RevokeUserSessions(rdb, userID); err != nil {
+		log.Println("failed to revoke sessions after password reset:", err)
+	}
+	notifyPasswordChanged(mq, email)
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "password reset, sign in with your new password"})
+}
+
+// ChangePassword replaces the password of the logged in user. Every other session is logged out,
+// the caller gets a fresh token pair.
+func ChangePassword(c echo.Context, db *sql.DB, rdb *redis.Client, mq *amqp.Connection) error {
+	ctx := c.Request().Context()
+	userID := c.Get("user_id").(int64)
+
+	req := new(models.ChangePasswordRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+	}
+
+	var (
+		email string
+		hash  sql.NullString
+	)
+	if err := db.QueryRowContext(ctx, `SELECT email, password_hash FROM users WHERE id = ?`, userID).Scan(&email, &hash); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if !hash.Valid || hash.String == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "no password set on this account, use forgot password to set one"})
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash.String), []byte(req.CurrentPassword)) != nil {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"error": "current password is wrong"})
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "new password must differ from the current one"})
+	}
+	if err := library.CheckPasswordStrength(req.NewPassword, email); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	newHash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to hash password"})
+	}
+	if _, err := db.ExecContext(ctx, `UPDATE users SET password_hash = ? WHERE id = ?`, string(newHash), userID); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	// pending reset links would bring the old account state back
+	if _, err := db.ExecContext(ctx, `UPDATE password_resets SET used_at = NOW() WHERE user_id = ? AND used_at IS NULL`, userID); err != nil {
+		log.Println("failed to expire password resets:", err)
+	}
+
+	if err := RevokeUserSessions(rdb, userID); err != nil {
+		log.Println("failed to revoke sessions after password change:", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to revoke sessions"})
+	}
+	notifyPasswordChanged(mq, email)
+
+	tokens, err := IssueTokens(c, db, rdb, userID)
+	if err != nil {
+		return tokenError(c, err)
+	}
+	return c.JSON(http.StatusOK, tokens)
+}
+
+func notifyPasswordChanged(mq *amqp.Connection, email string) {
+	notification := models.Notification{
+		Type:    OTPChannelEmail,
+		To:      email,
+		Subject: "Your Savannah Store password was changed",
+		Message: "The password of your account was just changed and every session was signed out. If this was not you, reset your password right away.",
+	}
+	if err := library.Notification(mq, notification); err != nil {
+		log.Println("failed to send password change notice:", err)
+	}
+}
@@ -0,0 +1,421 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"savannah-store/auth-service/internal/library"
+	"savannah-store/auth-service/internal/models"
+	"savannah-store/auth-service/internal/repository"
+	"savannah-store/pkg/mq"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	erasurePending = "pending"
+	erasureRunning = "running"
+	erasureDone    = "done"
+	erasureFailed  = "failed"
+	// a failed job is retried until it has been attempted this often, requesting the erasure again starts over
+	erasureMaxAttempts = 5
+	// how often the erasure worker looks for jobs, ERASURE_INTERVAL (seconds)
+	defaultErasureInterval = time.Minute
+)
+
+// personalDataServices hold personal data besides auth-service, each serves /internal/users/:id/data
+// for export (GET) and erasure (DELETE) and is reached through the base url in urlEnv
+var personalDataServices = []struct{ name, urlEnv string }{
+	{"order-service", "ORDER_SERVICE_URL"},
+	{"notification-service", "NOTIFICATION_SERVICE_URL"},
+}
+
+// ExportMyData downloads everything held about the calling user
+func ExportMyData(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	return exportPersonalData(c, db, rdb, c.Get("user_id").(int64))
+}
+
+// ExportUserData downloads everything held about the user :id
+func ExportUserData(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+	return exportPersonalData(c, db, rdb, userID)
+}
+
+// exportPersonalData collects the data of the user from every service and answers it as a zip holding one
+// JSON file per service, or as a single JSON document with ?format=json. A service that can not be reached
+// fails the export rather than leaving it silently incomplete.
+func exportPersonalData(c echo.Context, db *sql.DB, rdb *redis.Client, userID int64) error {
+	ctx := c.Request().Context()
+	format := c.QueryParam("format")
+	if format != "" && format != "zip" && format != "json" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "format must be zip or json"})
+	}
+
+	authData, err := authPersonalData(ctx, db, rdb, userID)
+	if err == repository.ErrUserNotFound {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	export := models.PersonalDataExport{
+		UserID:      userID,
+		GeneratedAt: time.Now().UTC(),
+		Auth:        authData,
+		Services:    map[string]json.RawMessage{},
+	}
+	for _, svc := range personalDataServices {
+		var data json.RawMessage
+		if err := library.CallService(ctx, http.MethodGet, svc.urlEnv, fmt.Sprintf("/internal/users/%d/data", userID), &data); err != nil {
+			log.Printf("failed to export personal data of user %d from %s: %v", userID, svc.name, err)
+			return c.JSON(http.StatusBadGateway, echo.Map{"error": "failed to collect data from " + svc.name})
+		}
+		export.Services[svc.name] = data
+	}
+
+	log.Printf("personal data of user %d exported by user %v", userID, c.Get("user_id"))
+	name := fmt.Sprintf("personal-data-%d-%s", userID, export.GeneratedAt.Format("20060102"))
+	if format == "json" {
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.json"`, name))
+		return c.JSON(http.StatusOK, export)
+	}
+
+	archive, err := exportArchive(export)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to build export"})
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.zip"`, name))
+	return c.Blob(http.StatusOK, "application/zip", archive)
+}
+
+// exportArchive writes the export as manifest.json plus one <service>.json per service
+func exportArchive(export models.PersonalDataExport) ([]byte, error) {
+	files := map[string]interface{}{"auth-service.json": export.Auth}
+	names := []string{"auth-service.json"}
+	for _, svc := range personalDataServices {
+		files[svc.name+".json"] = export.Services[svc.name]
+		names = append(names, svc.name+".json")
+	}
+	files["manifest.json"] = echo.Map{"user_id": export.UserID, "generated_at": export.GeneratedAt, "files": names}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range append([]string{"manifest.json"}, names...) {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: export.GeneratedAt})
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.MarshalIndent(files[name], "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func authPersonalData(ctx context.Context, db *sql.DB, rdb *redis.Client, userID int64) (*models.AuthPersonalData, error) {
+	user, err := repository.NewUserRepository(db).FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	data := &models.AuthPersonalData{
+		User:           *user,
+		RoleChanges:    []models.RoleChange{},
+		RoleRequests:   []models.RoleElevation{},
+		Impersonations: []models.Impersonation{},
+	}
+
+	if data.Identities, err = userIdentities(ctx, db, userID); err != nil {
+		return nil, err
+	}
+	if data.Sessions, err = userSessions(rdb, userID, ""); err != nil {
+		return nil, err
+	}
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) > 0 FROM user_mfa WHERE user_id = ? AND enabled_at IS NOT NULL`, userID).Scan(&data.MFAEnabled)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT COALESCE(o.name, ''), COALESCE(n.name, ''), rc.changed_by, rc.source, rc.created
+		FROM role_changes rc
+		LEFT JOIN roles o ON o.id = rc.old_role_id
+		LEFT JOIN roles n ON n.id = rc.new_role_id
+		WHERE rc.user_id = ?
+		ORDER BY rc.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			rc        models.RoleChange
+			changedBy sql.NullInt64
+		)
+		if err := rows.Scan(&rc.OldRole, &rc.NewRole, &changedBy, &rc.Source, &rc.Created); err != nil {
+			return nil, err
+		}
+		if changedBy.Valid {
+			rc.ChangedBy = &changedBy.Int64
+		}
+		data.RoleChanges = append(data.RoleChanges, rc)
+	}
+
+	requests, err := db.QueryContext(ctx, `
+		SELECT rr.id, rr.user_id, r.name, COALESCE(rr.reason, ''), rr.status, rr.decided_by, rr.decided_at, rr.created
+		FROM role_requests rr
+		JOIN roles r ON r.id = rr.role_id
+		WHERE rr.user_id = ?
+		ORDER BY rr.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer requests.Close()
+	for requests.Next() {
+		var (
+			r         models.RoleElevation
+			decidedBy sql.NullInt64
+			decidedAt sql.NullTime
+		)
+		if err := requests.Scan(&r.ID, &r.UserID, &r.Role, &r.Reason, &r.Status, &decidedBy, &decidedAt, &r.Created); err != nil {
+			return nil, err
+		}
+		r.Email = user.Email
+		if decidedBy.Valid {
+			r.DecidedBy = &decidedBy.Int64
+		}
+		if decidedAt.Valid {
+			r.DecidedAt = &decidedAt.Time
+		}
+		data.RoleRequests = append(data.RoleRequests, r)
+	}
+
+	impersonations, err := db.QueryContext(ctx, impersonationColumns+` WHERE user_id = ? ORDER BY started_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer impersonations.Close()
+	for impersonations.Next() {
+		imp, err := scanImpersonation(impersonations)
+		if err != nil {
+			return nil, err
+		}
+		data.Impersonations = append(data.Impersonations, *imp)
+	}
+
+	return data, nil
+}
+
+// RequestErasure queues the erasure of the personal data of the user :id. The account is disabled and
+// logged out at once, the data itself is erased in every service by the erasure worker.
+func RequestErasure(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	ctx := c.Request().Context()
+	adminID := c.Get("user_id").(int64)
+
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+	if userID == adminID {
+		return c.JSON(http.StatusForbidden, echo.Map{"error": "you can not erase your own account"})
+	}
+	if _, err := repository.NewUserRepository(db).FindByID(ctx, userID); err == repository.ErrUserNotFound {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": err.Error()})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	// a job that is still queued or running covers this request as well
+	var jobID int64
+	err = db.QueryRowContext(ctx, `SELECT id FROM erasure_jobs WHERE user_id = ? AND status IN (?, ?) ORDER BY id DESC LIMIT 1`,
+		userID, erasurePending, erasureRunning).Scan(&jobID)
+	if err == sql.ErrNoRows {
+		res, err := db.ExecContext(ctx, `INSERT INTO erasure_jobs (user_id, requested_by, status) VALUES (?, ?, ?)`, userID, adminID, erasurePending)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		jobID, _ = res.LastInsertId()
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	if _, err := db.ExecContext(ctx, `UPDATE users SET disabled_at = COALESCE(disabled_at, NOW()) WHERE id = ?`, userID); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if err := RevokeUserSessions(rdb, userID); err != nil {
+		log.Println("failed to revoke sessions of user queued for erasure:", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to revoke sessions"})
+	}
+
+	job, err := loadErasureJob(ctx, db, jobID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	log.Printf("erasure of user %d requested by admin %d (job %d)", userID, adminID, jobID)
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// GetErasureJob returns the progress of the erasure job :id
+func GetErasureJob(c echo.Context, db *sql.DB) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid job id"})
+	}
+	job, err := loadErasureJob(c.Request().Context(), db, id)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "erasure job not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, job)
+}
+
+func loadErasureJob(ctx context.Context, db *sql.DB, id int64) (*models.ErasureJob, error) {
+	job := &models.ErasureJob{}
+	var completedAt sql.NullTime
+	err := db.QueryRowContext(ctx, `
+		SELECT id, user_id, requested_by, status, attempts, COALESCE(last_error, ''), created, updated, completed_at
+		FROM erasure_jobs WHERE id = ?`, id,
+	).Scan(&job.ID, &job.UserID, &job.RequestedBy, &job.Status, &job.Attempts, &job.LastError, &job.Created, &job.Updated, &completedAt)
+	if err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+	return job, nil
+}
+
+// RunErasureJobs works off the erasure jobs every ERASURE_INTERVAL seconds, it never returns
+func RunErasureJobs(db *sql.DB, rdb *redis.Client, pub *mq.Publisher) {
+	interval := time.Duration(envInt("ERASURE_INTERVAL", int(defaultErasureInterval.Seconds()))) * time.Second
+	for {
+		processErasureJobs(db, rdb, pub)
+		time.Sleep(interval)
+	}
+}
+
+// processErasureJobs runs the queued jobs, failed jobs that have attempts left, and running jobs whose
+// worker died (not updated for ten minutes)
+func processErasureJobs(db *sql.DB, rdb *redis.Client, pub *mq.Publisher) {
+	ctx := context.Background()
+	const runnable = `(status = 'pending' OR (status = 'failed' AND attempts < ?) OR (status = 'running' AND updated < NOW() - INTERVAL 10 MINUTE))`
+
+	rows, err := db.QueryContext(ctx, `SELECT id, user_id, requested_by FROM erasure_jobs WHERE `+runnable+` ORDER BY id LIMIT 20`, erasureMaxAttempts)
+	if err != nil {
+		log.Println("failed to fetch erasure jobs:", err)
+		return
+	}
+	var jobs []models.ErasureJob
+	for rows.Next() {
+		var job models.ErasureJob
+		if err := rows.Scan(&job.ID, &job.UserID, &job.RequestedBy); err != nil {
+			log.Println("failed to read erasure job:", err)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	rows.Close()
+
+	for _, job := range jobs {
+		// claim the job, another instance may have taken it in the meantime
+		res, err := db.ExecContext(ctx, `UPDATE erasure_jobs SET status = ?, attempts = attempts + 1 WHERE id = ? AND `+runnable,
+			erasureRunning, job.ID, erasureMaxAttempts)
+		if err != nil {
+			log.Println("failed to claim erasure job:", err)
+			continue
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+
+		if err := eraseUser(ctx, db, rdb, job.UserID); err != nil {
+			log.Printf("erasure job %d of user %d failed: %v", job.ID, job.UserID, err)
+			if _, err := db.ExecContext(ctx, `UPDATE erasure_jobs SET status = ?, last_error = ? WHERE id = ?`, erasureFailed, err.Error(), job.ID); err != nil {
+				log.Println("failed to record erasure failure:", err)
+			}
+			continue
+		}
+
+		if _, err := db.ExecContext(ctx, `UPDATE erasure_jobs SET status = ?, last_error = NULL, completed_at = NOW() WHERE id = ?`, erasureDone, job.ID); err != nil {
+			log.Println("failed to complete erasure job:", err)
+		}
+		log.Printf("erasure job %d done, personal data of user %d erased", job.ID, job.UserID)
+		publishEvent(pub, EventUserErased, models.UserErasedEvent{
+			UserID:      job.UserID,
+			JobID:       job.ID,
+			RequestedBy: job.RequestedBy,
+			ErasedAt:    time.Now().UTC(),
+		})
+	}
+}
+
+// eraseUser anonymises the user in the other services first and in auth-service last, so a failed attempt
+// can be retried with the user still known. Every step can run twice without harm.
+func eraseUser(ctx context.Context, db *sql.DB, rdb *redis.Client, userID int64) error {
+	for _, svc := range personalDataServices {
+		if err := library.CallService(ctx, http.MethodDelete, svc.urlEnv, fmt.Sprintf("/internal/users/%d/data", userID), nil); err != nil {
+			return fmt.Errorf("%s: %v", svc.name, err)
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the email is unique, so the placeholder carries the id
+	erased := fmt.Sprintf("erased-%d@erased.invalid", userID)
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE role_invites SET email = ? WHERE accepted_by = ? OR email = (SELECT email FROM users WHERE id = ?)`, []interface{}{erased, userID, userID}},
+		{`UPDATE role_requests SET reason = NULL WHERE user_id = ?`, []interface{}{userID}},
+		{`DELETE FROM user_identities WHERE user_id = ?`, []interface{}{userID}},
+		{`DELETE FROM user_mfa WHERE user_id = ?`, []interface{}{userID}},
+		{`DELETE FROM mfa_recovery_codes WHERE user_id = ?`, []interface{}{userID}},
+		{`DELETE FROM password_resets WHERE user_id = ?`, []interface{}{userID}},
+		{`
+			UPDATE users
+			SET email = ?, email_verified = 0, phone = NULL, phone_verified = 0, full_name = NULL, password_hash = NULL,
+			    otp = NULL, otp_purpose = NULL, otp_channel = NULL, otp_expires_at = NULL, otp_attempts = 0,
+			    disabled_at = COALESCE(disabled_at, NOW()), deleted_at = COALESCE(deleted_at, NOW()),
+			    erased_at = COALESCE(erased_at, NOW())
+			WHERE id = ?`, []interface{}{erased, userID}},
+	}
+	for _, s := range statements {
+		if _, err := tx.ExecContext(ctx, s.query, s.args...); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := RevokeUserSessions(rdb, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %v", err)
+	}
+	clearLoginFailures(rdb, userID)
+	return nil
+}
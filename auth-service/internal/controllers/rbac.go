@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"savannah-store/auth-service/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HasPermission checks whether the role of a user is granted action on module
+func HasPermission(ctx context.Context, db *sql.DB, userID int64, module, action string) (bool, error) {
+	var allowed bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1
+			FROM users u
+			JOIN role_permissions rp ON rp.role_id = u.role_id
+			JOIN permissions p ON p.id = rp.permission_id
+			JOIN modules m ON m.id = p.module_id
+			WHERE u.id = ? AND m.name = ? AND p.action = ?
+		)`, userID, module, action,
+	).Scan(&allowed)
+	return allowed, err
+}
+
+// ListRoles returns every role with its permissions
+func ListRoles(c echo.Context, db *sql.DB) error {
+	ctx := c.Request().Context()
+
+	rows, err := db.QueryContext(ctx, `SELECT id, name, COALESCE(description, '') FROM roles ORDER BY id`)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		roles = append(roles, role)
+	}
+
+	for i := range roles {
+		perms, err := rolePermissions(ctx, db, roles[i].ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		roles[i].Permissions = perms
+	}
+
+	return c.JSON(http.StatusOK, roles)
+}
+
+// CreateRole inserts a new role without permissions
+func CreateRole(c echo.Context, db *sql.DB) error {
+	req := new(models.RoleRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	req.Name = strings.ToLower(strings.TrimSpace(req.Name))
+	if req.Name == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "name is required"})
+	}
+
+	res, err := db.ExecContext(c.Request().Context(), `INSERT INTO roles (name, description) VALUES (?, ?)`, req.Name, req.Description)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return c.JSON(http.StatusConflict, echo.Map{"error": "role already exists"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	id, _ := res.LastInsertId()
+	return c.JSON(http.StatusCreated, models.Role{ID: id, Name: req.Name, Description: req.Description, Permissions: []string{}})
+}
+
+// GrantPermission grants :action on :module to the role :id
+func GrantPermission(c echo.Context, db *sql.DB) error {
+	ctx := c.Request().Context()
+
+	roleID, permissionID, httpErr := resolveRolePermission(c, db)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+
+	if _, err := db.ExecContext(ctx, `INSERT IGNORE INTO role_permissions (role_id, permission_id) VALUES (?, ?)`, roleID, permissionID); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "permission granted"})
+}
+
+// RevokePermission removes :action on :module from the role :id
+func RevokePermission(c echo.Context, db *sql.DB) error {
+	ctx := c.Request().Context()
+
+	roleID, permissionID, httpErr := resolveRolePermission(c, db)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+
+	if _, err := db.ExecContext(ctx, `DELETE FROM role_permissions WHERE role_id = ? AND permission_id = ?`, roleID, permissionID); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "permission revoked"})
+}
+
+// resolveRolePermission looks up the role and permission named in the path
+func resolveRolePermission(c echo.Context, db *sql.DB) (int64, int64, *echo.HTTPError) {
+	ctx := c.Request().Context()
+
+	roleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "invalid role id")
+	}
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM roles WHERE id = ?)`, roleID).Scan(&exists); err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if !exists {
+		return 0, 0, echo.NewHTTPError(http.StatusNotFound, "role not found")
+	}
+
+	var permissionID int64
+	err = db.QueryRowContext(ctx, `
+		SELECT p.id
+		FROM permissions p
+		JOIN modules m ON m.id = p.module_id
+		WHERE m.name = ? AND p.action = ?`, c.Param("module"), c.Param("action"),
+	).Scan(&permissionID)
+	if err == sql.ErrNoRows {
+		return 0, 0, echo.NewHTTPError(http.StatusNotFound, "permission not found")
+	}
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return roleID, permissionID, nil
+}
+
+// ListPermissions returns every module/action permission
+func ListPermissions(c echo.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(c.Request().Context(), `
+		SELECT p.id, m.name, p.action, COALESCE(p.description, '')
+		FROM permissions p
+		JOIN modules m ON m.id = p.module_id
+		ORDER BY m.name, p.id`)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer rows.Close()
+
+	permissions := []models.Permission{}
+	for rows.Next() {
+		var p models.Permission
+		if err := rows.Scan(&p.ID, &p.Module, &p.Action, &p.Description); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		permissions = append(permissions, p)
+	}
+
+	return c.JSON(http.StatusOK, permissions)
+}
+
+// CreateModule registers a module and seeds its default permissions
+func CreateModule(c echo.Context, db *sql.DB) error {
+	ctx := c.Request().Context()
+
+	req := new(models.ModuleRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	req.Name = strings.ToLower(strings.TrimSpace(req.Name))
+	if req.Name == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "name is required"})
+	}
+
+	if _, err := db.ExecContext(ctx, `INSERT IGNORE INTO modules (name) VALUES (?)`, req.Name); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	if err := seedPermissions(ctx, db); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{"message": "module created", "name": req.Name})
+}
+
+// SeedPermissions makes sure every module has its default permissions and that admin holds all of them
+func SeedPermissions(c echo.Context, db *sql.DB) error {
+	if err := seedPermissions(c.Request().Context(), db); err != nil {
+		log.Println("failed to seed permissions:", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "permissions seeded"})
+}
+
+func seedPermissions(ctx context.Context, db *sql.DB) error {
+	for _, action := range models.PermissionActions {
+		_, err := db.ExecContext(ctx, `
+			INSERT IGNORE INTO permissions (module_id, action, description)
+			SELECT id, ?, CONCAT(?, ' ', name) FROM modules`, action, action)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := db.ExecContext(ctx, `
+		INSERT IGNORE INTO role_permissions (role_id, permission_id)
+		SELECT r.id, p.id FROM roles r CROSS JOIN permissions p WHERE r.name = 'admin'`)
+	return err
+}
@@ -0,0 +1,438 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/url"
+	"os"
+	"savannah-store/auth-service/internal/library"
+	"savannah-store/auth-service/internal/models"
+	"savannah-store/pkg/mq"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// where a role change came from, stored in role_changes.source
+const (
+	RoleChangeSourceInvite  = "invite"
+	RoleChangeSourceRequest = "request"
+	RoleChangeSourceAdmin   = "admin"
+)
+
+// CustomerRoleID returns the id of the role every new account starts with
+func CustomerRoleID(ctx context.Context, db *sql.DB) (int64, error) {
+	var roleID int64
+	err := db.QueryRowContext(ctx, `SELECT id FROM roles WHERE name = 'customer'`).Scan(&roleID)
+	return roleID, err
+}
+
+// CreateInvite issues a signed invitation granting a role to whoever signs in with the invited email
+func CreateInvite(c echo.Context, db *sql.DB) error {
+	ctx := c.Request().Context()
+	adminID := c.Get("user_id").(int64)
+
+	req := new(models.InviteRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	req.Role = strings.ToLower(strings.TrimSpace(req.Role))
+	if req.Email == "" || req.Role == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "email and role are required"})
+	}
+
+	roleID, err := roleIDByName(ctx, db, req.Role)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "role not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	id, err := library.RandomToken(16)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to generate invite"})
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	invite := models.RoleInvite{
+		ID:        id,
+		Email:     req.Email,
+		Role:      req.Role,
+		InvitedBy: adminID,
+		ExpiresAt: now.Add(library.InviteTokenTTL()),
+		Created:   now,
+	}
+
+	_, err = db.ExecContext(ctx, `INSERT INTO role_invites (id, email, role_id, invited_by, expires_at, created) VALUES (?, ?, ?, ?, ?, ?)`,
+		invite.ID, invite.Email, roleID, invite.InvitedBy, invite.ExpiresAt, invite.Created)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	invite.Token, err = library.SignTypedToken(models.InviteClaims{
+		Email: invite.Email,
+		Role:  invite.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        invite.ID,
+			IssuedAt:  jwt.NewNumericDate(invite.Created),
+			ExpiresAt: jwt.NewNumericDate(invite.ExpiresAt),
+		},
+	}, library.InviteTokenType)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to sign invite"})
+	}
+
+	// INVITE_URL is the page that starts the signup flow with the invite, e.g. https://shop.example.com/signup
+	if base := os.Getenv("INVITE_URL"); base != "" {
+		invite.URL = base + "?invite=" + url.QueryEscape(invite.Token)
+	}
+
+	return c.JSON(http.StatusCreated, invite)
+}
+
+// ListInvites returns the invitations, newest first
+func ListInvites(c echo.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(c.Request().Context(), `
+		SELECT i.id, i.email, r.name, i.invited_by, i.expires_at, i.accepted_by, i.accepted_at, i.created
+		FROM role_invites i
+		JOIN roles r ON r.id = i.role_id
+		ORDER BY i.created DESC`)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer rows.Close()
+
+	invites := []models.RoleInvite{}
+	for rows.Next() {
+		var (
+			invite     models.RoleInvite
+			acceptedBy sql.NullInt64
+			acceptedAt sql.NullTime
+		)
+		if err := rows.Scan(&invite.ID, &invite.Email, &invite.Role, &invite.InvitedBy, &invite.ExpiresAt, &acceptedBy, &acceptedAt, &invite.Created); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		if acceptedBy.Valid {
+			invite.AcceptedBy = &acceptedBy.Int64
+		}
+		if acceptedAt.Valid {
+			invite.AcceptedAt = &acceptedAt.Time
+		}
+		invites = append(invites, invite)
+	}
+
+	return c.JSON(http.StatusOK, invites)
+}
+
+// AcceptInvite lets the logged in user redeem an invitation sent to their email
+func AcceptInvite(c echo.Context, db *sql.DB, pub *mq.Publisher) error {
+	userID := c.Get("user_id").(int64)
+
+	req := new(models.AcceptInviteRequest)
+	if err := c.Bind(req); err != nil || req.Invite == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invite is required"})
+	}
+
+	role, httpErr := RedeemInvite(c.Request().Context(), db, pub, userID, req.Invite)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "invite accepted, refresh your token to use the new role", "role": role})
+}
+
+// RedeemInvite checks a signed invite against the user and moves the user to the invited role.
+// An invite can only be used once, before it expires and by a verified account with the invited email.
+func RedeemInvite(ctx context.Context, db *sql.DB, pub *mq.Publisher, userID int64, token string) (string, *echo.HTTPError) {
+	claims := &models.InviteClaims{}
+	if err := library.ParseTypedToken(token, claims, library.InviteTokenType); err != nil {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "invalid or expired invite")
+	}
+
+	var (
+		email    string
+		verified bool
+	)
+	err := db.QueryRowContext(ctx, `SELECT email, email_verified FROM users WHERE id = ?`, userID).Scan(&email, &verified)
+	if err == sql.ErrNoRows {
+		return "", echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if !strings.EqualFold(email, claims.Email) {
+		return "", echo.NewHTTPError(http.StatusForbidden, "invite was issued for another email")
+	}
+	if !verified {
+		return "", echo.NewHTTPError(http.StatusForbidden, "verify your email before accepting an invite")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer tx.Rollback()
+
+	// the role is taken from the stored invite, the token only identifies it
+	var roleID, invitedBy int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT role_id, invited_by FROM role_invites
+		WHERE id = ? AND accepted_at IS NULL AND expires_at > NOW()
+		FOR UPDATE`, claims.ID,
+	).Scan(&roleID, &invitedBy)
+	if err == sql.ErrNoRows {
+		return "", echo.NewHTTPError(http.StatusConflict, "invite already used or expired")
+	}
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE role_invites SET accepted_by = ?, accepted_at = NOW() WHERE id = ?`, userID, claims.ID); err != nil {
+		return "", echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	event, err := changeUserRole(ctx, tx, userID, roleID, invitedBy, RoleChangeSourceInvite)
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		return "", echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	publishRoleChange(pub, event)
+	return claims.Role, nil
+}
+
+// RequestRole files a request by the logged in user to be given a role
+func RequestRole(c echo.Context, db *sql.DB) error {
+	ctx := c.Request().Context()
+	userID := c.Get("user_id").(int64)
+
+	req := new(models.ElevationRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+	req.Role = strings.ToLower(strings.TrimSpace(req.Role))
+	if req.Role == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "role is required"})
+	}
+
+	roleID, err := roleIDByName(ctx, db, req.Role)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "role not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	var hasRole, pending bool
+	err = db.QueryRowContext(ctx, `
+		SELECT
+			EXISTS(SELECT 1 FROM users WHERE id = ? AND role_id = ?),
+			EXISTS(SELECT 1 FROM role_requests WHERE user_id = ? AND role_id = ? AND status = 'pending')`,
+		userID, roleID, userID, roleID,
+	).Scan(&hasRole, &pending)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if hasRole {
+		return c.JSON(http.StatusConflict, echo.Map{"error": "you already have this role"})
+	}
+	if pending {
+		return c.JSON(http.StatusConflict, echo.Map{"error": "a request for this role is already pending"})
+	}
+
+	res, err := db.ExecContext(ctx, `INSERT INTO role_requests (user_id, role_id, reason) VALUES (?, ?, ?)`, userID, roleID, req.Reason)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	id, _ := res.LastInsertId()
+
+	return c.JSON(http.StatusCreated, models.RoleElevation{
+		ID:      id,
+		UserID:  userID,
+		Email:   c.Get("email").(string),
+		Role:    req.Role,
+		Reason:  req.Reason,
+		Status:  "pending",
+		Created: time.Now().UTC(),
+	})
+}
+
+// ListRoleRequests returns the role requests with the given ?status (default pending)
+func ListRoleRequests(c echo.Context, db *sql.DB) error {
+	status := c.QueryParam("status")
+	if status == "" {
+		status = "pending"
+	}
+	if status != "pending" && status != "approved" && status != "rejected" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "status must be pending, approved or rejected"})
+	}
+
+	rows, err := db.QueryContext(c.Request().Context(), `
+		SELECT rr.id, rr.user_id, u.email, r.name, COALESCE(rr.reason, ''), rr.status, rr.decided_by, rr.decided_at, rr.created
+		FROM role_requests rr
+		JOIN users u ON u.id = rr.user_id
+		JOIN roles r ON r.id = rr.role_id
+		WHERE rr.status = ?
+		ORDER BY rr.created`, status)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer rows.Close()
+
+	requests := []models.RoleElevation{}
+	for rows.Next() {
+		var (
+			r         models.RoleElevation
+			decidedBy sql.NullInt64
+			decidedAt sql.NullTime
+		)
+		if err := rows.Scan(&r.ID, &r.UserID, &r.Email, &r.Role, &r.Reason, &r.Status, &decidedBy, &decidedAt, &r.Created); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		if decidedBy.Valid {
+			r.DecidedBy = &decidedBy.Int64
+		}
+		if decidedAt.Valid {
+			r.DecidedAt = &decidedAt.Time
+		}
+		requests = append(requests, r)
+	}
+
+	return c.JSON(http.StatusOK, requests)
+}
+
+// ApproveRoleRequest grants the requested role to the user
+func ApproveRoleRequest(c echo.Context, db *sql.DB, pub *mq.Publisher) error {
+	return decideRoleRequest(c, db, pub, "approved")
+}
+
+// RejectRoleRequest closes the request without changing the user's role
+func RejectRoleRequest(c echo.Context, db *sql.DB) error {
+	return decideRoleRequest(c, db, nil, "rejected")
+}
+
+func decideRoleRequest(c echo.Context, db *sql.DB, pub *mq.Publisher, status string) error {
+	ctx := c.Request().Context()
+	adminID := c.Get("user_id").(int64)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request id"})
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer tx.Rollback()
+
+	var (
+		userID, roleID int64
+		current        string
+	)
+	err = tx.QueryRowContext(ctx, `SELECT user_id, role_id, status FROM role_requests WHERE id = ? FOR UPDATE`, id).Scan(&userID, &roleID, &current)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "request not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if current != "pending" {
+		return c.JSON(http.StatusConflict, echo.Map{"error": "request was already " + current})
+	}
+	if userID == adminID {
+		return c.JSON(http.StatusForbidden, echo.Map{"error": "you can not decide your own request"})
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE role_requests SET status = ?, decided_by = ?, decided_at = NOW() WHERE id = ?`, status, adminID, id); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	var event *models.RoleChangedEvent
+	if status == "approved" {
+		event, err = changeUserRole(ctx, tx, userID, roleID, adminID, RoleChangeSourceRequest)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	publishRoleChange(pub, event)
+	return c.JSON(http.StatusOK, echo.Map{"message": "request " + status})
+}
+
+// changeUserRole moves the user to roleID and records the change in role_changes.
+// It returns nil when the user already has the role. Access tokens already issued keep
+// the old role until the user refreshes.
+func changeUserRole(ctx context.Context, tx *sql.Tx, userID, roleID, changedBy int64, source string) (*models.RoleChangedEvent, error) {
+	var (
+		email     string
+		oldRoleID sql.NullInt64
+		oldRole   string
+	)
+	err := tx.QueryRowContext(ctx, `
+		SELECT u.email, u.role_id, COALESCE(r.name, '')
+		FROM users u
+		LEFT JOIN roles r ON r.id = u.role_id
+		WHERE u.id = ?
+		FOR UPDATE`, userID,
+	).Scan(&email, &oldRoleID, &oldRole)
+	if err != nil {
+		return nil, err
+	}
+	if oldRoleID.Valid && oldRoleID.Int64 == roleID {
+		return nil, nil
+	}
+
+	var newRole string
+	if err := tx.QueryRowContext(ctx, `SELECT name FROM roles WHERE id = ?`, roleID).Scan(&newRole); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET role_id = ? WHERE id = ?`, roleID, userID); err != nil {
+		return nil, err
+	}
+
+	var by sql.NullInt64
+	if changedBy != 0 {
+		by = sql.NullInt64{Int64: changedBy, Valid: true}
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO role_changes (user_id, old_role_id, new_role_id, changed_by, source) VALUES (?, ?, ?, ?, ?)`,
+		userID, oldRoleID, roleID, by, source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RoleChangedEvent{
+		UserID:    userID,
+		Email:     email,
+		OldRole:   oldRole,
+		NewRole:   newRole,
+		ChangedBy: changedBy,
+		Source:    source,
+		ChangedAt: time.Now().UTC(),
+	}, nil
+}
+
+// publishRoleChange announces a committed role change, a failed publish does not undo the change
+func publishRoleChange(pub *mq.Publisher, event *models.RoleChangedEvent) {
+	if event == nil {
+		return
+	}
+	publishEvent(pub, EventUserRoleChanged, event)
+}
+
+func roleIDByName(ctx context.Context, db *sql.DB, name string) (int64, error) {
+	var roleID int64
+	err := db.QueryRowContext(ctx, `SELECT id FROM roles WHERE name = ?`, name).Scan(&roleID)
+	return roleID, err
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"savannah-store/auth-service/internal/models"
+	"sort"
+	"strconv"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+)
+
+// ListSessions returns the sessions of the logged in user, the one making the request is marked current
+func ListSessions(c echo.Context, rdb *redis.Client) error {
+	claims := c.Get("claims").(*models.JwtCustomClaims)
+
+	sessions, err := userSessions(rdb, claims.UserID, claims.SessionID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, sessions)
+}
+
+// DeleteSession logs one session of the logged in user out
+func DeleteSession(c echo.Context, rdb *redis.Client) error {
+	return deleteSession(c, rdb, c.Get("user_id").(int64), c.Param("id"))
+}
+
+// ListUserSessions returns the sessions of the user :id
+func ListUserSessions(c echo.Context, rdb *redis.Client) error {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+
+	sessions, err := userSessions(rdb, userID, "")
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, sessions)
+}
+
+// DeleteUserSession logs the session :sid of the user :id out
+func DeleteUserSession(c echo.Context, rdb *redis.Client) error {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+	return deleteSession(c, rdb, userID, c.Param("sid"))
+}
+
+// userSessions loads the live sessions of a user, most recently used first. Families whose refresh
+// tokens expired are dropped from the user's set on the way.
+func userSessions(rdb *redis.Client, userID int64, current string) ([]models.Session, error) {
+	families, err := rdb.SMembers(userFamiliesKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []models.Session{}
+	for _, family := range families {
+		session := loadSession(rdb, family)
+		if session == nil {
+			rdb.SRem(userFamiliesKey(userID), family)
+			continue
+		}
+		session.Current = family == current
+		sessions = append(sessions, *session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeen.After(sessions[j].LastSeen) })
+	return sessions, nil
+}
+
+// deleteSession revokes the refresh tokens of the session and, through the sid denylist, its access tokens
+func deleteSession(c echo.Context, rdb *redis.Client, userID int64, sessionID string) error {
+	member, err := rdb.SIsMember(userFamiliesKey(userID), sessionID).Result()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if !member {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "session not found"})
+	}
+
+	if err := revokeFamily(rdb, userID, sessionID); err != nil {
+		log.Println("failed to revoke session:", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to revoke session"})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": "session logged out"})
+}
@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"savannah-store/auth-service/internal/library"
+	"savannah-store/auth-service/internal/models"
+	"savannah-store/pkg/redisx"
+
+	"github.com/go-redis/redis"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// RefreshTokenRequest is the payload for exchanging a refresh token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// refreshRecord is what we keep in redis for every issued refresh token
+type refreshRecord struct {
+	UserID int64  `json:"user_id"`
+	Family string `json:"family"`
+}
+
+// ErrAccountDisabled is returned instead of tokens for disabled and deleted accounts
+var ErrAccountDisabled = errors.New("account is disabled")
+
+func refreshTokenKey(hash string) string { return fmt.Sprintf("refresh:token:%s", hash) }
+func refreshUsedKey(hash string) string  { return fmt.Sprintf("refresh:used:%s", hash) }
+func refreshFamilyKey(family string) string {
+	return fmt.Sprintf("refresh:family:%s", family)
+}
+func userFamiliesKey(userID int64) string {
+	return fmt.Sprintf("refresh:user:%d", userID)
+}
+
+// IssueTokens creates an access token and a refresh token that starts a new token family,
+// recorded as a session of the device making the request
+func IssueTokens(c echo.Context, db *sql.DB, rdb *redis.Client, userID int64) (*UserLoginResponse, error) {
+	family, err := library.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return issueTokensInFamily(c, db, rdb, userID, family)
+}
+
+// tokenError answers a request whose tokens could not be issued
+func tokenError(c echo.Context, err error) error {
+	if errors.Is(err, ErrAccountDisabled) {
+		return c.JSON(http.StatusForbidden, echo.Map{"error": err.Error()})
+	}
+	log.Println("failed to issue tokens:", err)
+	return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to generate token"})
+}
+
+func issueTokensInFamily(c echo.Context, db *sql.DB, rdb *redis.Client, userID int64, family string) (*UserLoginResponse, error) {
+	claims, err := userClaims(c.Request().Context(), db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	library.AwaitRevocationSecond(rdb, userID)
+	now := time.Now()
+	expiry := now.Add(library.AccessTokenTTL())
+	jti, err := library.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	claims.SessionID = family
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        jti,
+		Subject:   strconv.FormatInt(userID, 10),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiry),
+	}
+	accessToken, err := library.SignToken(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign token: %v", err)
+	}
+
+	refreshToken, err := library.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	refreshTTL := library.RefreshTokenTTL()
+	record, _ := json.Marshal(refreshRecord{UserID: userID, Family: family})
+
+	// the family key is what makes every token of the family valid, so it slides with each rotation.
+	// It holds the session, which keeps its device and creation time across rotations.
+	session := loadSession(rdb, family)
+	if session == nil || session.UserID != userID {
+		session = &models.Session{
+			ID:      family,
+			UserID:  userID,
+			Device:  library.DeviceName(c.Request().UserAgent()),
+			Created: now.UTC(),
+		}
+	}
+	session.IP = c.RealIP()
+	session.UserAgent = c.Request().UserAgent()
+	session.LastSeen = now.UTC()
+	sessionData, _ := json.Marshal(session)
+	if err := redisx.SetWithExpiry(rdb, refreshFamilyKey(family), string(sessionData), int(refreshTTL.Seconds())); err != nil {
+		return nil, err
+	}
+	if err := redisx.SetWithExpiry(rdb, refreshTokenKey(library.HashToken(refreshToken)), string(record), int(refreshTTL.Seconds())); err != nil {
+		return nil, err
+	}
+
+	// remember the families of the user so they can all be revoked at once
+	familiesKey := userFamiliesKey(userID)
+	if err := rdb.SAdd(familiesKey, family).Err(); err != nil {
+		return nil, err
+	}
+	rdb.Expire(familiesKey, refreshTTL)
+
+	return &UserLoginResponse{
+		Token:            accessToken,
+		ExpiresAt:        expiry,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: now.Add(refreshTTL),
+	}, nil
+}
+
+// RefreshTokens rotates a refresh token. Presenting a refresh token that was already rotated
+// is treated as theft and revokes every token of its family.
+func RefreshTokens(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	var req RefreshTokenRequest
+	if err := c.Bind(&req); err != nil || req.RefreshToken == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "missing refresh_token"})
+	}
+
+	hash := library.HashToken(req.RefreshToken)
+	data, err := redisx.Get(rdb, refreshTokenKey(hash))
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid refresh token"})
+	}
+
+	var record refreshRecord
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid refresh token"})
+	}
+
+	if n, err := rdb.Exists(refreshFamilyKey(record.Family)).Result(); err != nil || n == 0 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "refresh token revoked"})
+	}
+
+	// SETNX makes sure only one caller can ever rotate a given refresh token
+	firstUse, err := rdb.SetNX(refreshUsedKey(hash), 1, library.RefreshTokenTTL()).Result()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+	if !firstUse {
+		log.Printf("refresh token reuse detected for user %d, revoking family %s", record.UserID, record.Family)
+		if err := revokeFamily(rdb, record.UserID, record.Family); err != nil {
+			log.Println("failed to revoke token family:", err)
+		}
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "refresh token reuse detected"})
+	}
+
+	resp, err := issueTokensInFamily(c, db, rdb, record.UserID, record.Family)
+	if err != nil {
+		return tokenError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// revokeFamily invalidates every refresh token of a family and the access tokens issued with them
+func revokeFamily(rdb *redis.Client, userID int64, family string) error {
+	if err := library.RevokeSession(rdb, family); err != nil {
+		return err
+	}
+	if err := redisx.Delete(rdb, refreshFamilyKey(family)); err != nil {
+		return err
+	}
+	return rdb.SRem(userFamiliesKey(userID), family).Err()
+}
+
+// loadSession returns the session of a live token family, nil when there is none
+func loadSession(rdb *redis.Client, family string) *models.Session {
+	data, err := redisx.Get(rdb, refreshFamilyKey(family))
+	if err != nil {
+		return nil
+	}
+	session := &models.Session{}
+	if err := json.Unmarshal([]byte(data), session); err != nil {
+		return nil
+	}
+	return session
+}
+
+// revokeAllFamilies invalidates every refresh token issued to the user
+func revokeAllFamilies(rdb *redis.Client, userID int64) error {
+	families, err := rdb.SMembers(userFamiliesKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+	for _, family := range families {
+		if err := redisx.Delete(rdb, refreshFamilyKey(family)); err != nil {
+			return err
+		}
+	}
+	return redisx.Delete(rdb, userFamiliesKey(userID))
+}
+
+// userClaims loads the identity and permissions that go into an access token
+func userClaims(ctx context.Context, db *sql.DB, userID int64) (*models.JwtCustomClaims, error) {
+	var (
+		email    string
+		roleID   sql.NullInt64
+		role     sql.NullString
+		disabled bool
+	)
+	err := db.QueryRowContext(ctx, `
+		SELECT u.email, u.role_id, r.name, u.disabled_at IS NOT NULL OR u.deleted_at IS NOT NULL
+		FROM users u
+		LEFT JOIN roles r ON r.id = u.role_id
+		WHERE u.id = ?`, userID,
+	).Scan(&email, &roleID, &role, &disabled)
+	if err != nil {
+		return nil, err
+	}
+	if disabled {
+		return nil, ErrAccountDisabled
+	}
+
+	perms, err := rolePermissions(ctx, db, roleID.Int64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.JwtCustomClaims{
+		UserID: userID,
+		Email:  email,
+		RoleID: roleID.Int64,
+		Role:   role.String,
+		Perms:  perms,
+	}, nil
+}
+
+// rolePermissions returns the permissions of a role as "module:action" strings
+func rolePermissions(ctx context.Context, db *sql.DB, roleID int64) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT CONCAT(m.name, ':', p.action)
+		FROM permissions p
+		JOIN modules m ON m.id = p.module_id
+		JOIN role_permissions rp ON rp.permission_id = p.id
+		WHERE rp.role_id = ?`, roleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	perms := []string{}
+	for rows.Next() {
+		var perm string
+		if err := rows.Scan(&perm); err != nil {
+			return nil, err
+		}
+		perms = append(perms, perm)
+	}
+	return perms, rows.Err()
+}
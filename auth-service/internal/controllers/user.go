@@ -1,13 +1,13 @@
 package controllers
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
 	"log"
 	"net/http"
 	"savannah-store/auth-service/internal/library"
-
-	"github.com/golang-jwt/jwt/v5"
+	"savannah-store/pkg/mq"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis"
@@ -28,7 +28,6 @@ type UserCreateRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Phone    string `json:"phone"`
-	RoleName string `json:"role_name"`
 }
 
 
@@ -38,94 +37,92 @@ type UserLoginRequest struct {
 }
 
 type UserLoginResponse struct {
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
 }
 
-func UserLogin(c echo.Context, db *sql.DB, rdb *redis.Client, jwtSecret string) error {
+// UserLogin checks email (or an unambiguous phone number) and password and issues an access and refresh token pair
+func UserLogin(c echo.Context, db *sql.DB, rdb *redis.Client, pub *mq.Publisher) error {
 	var req UserLoginRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
 	}
 
-	// Fetch user from DB
-	var (
-		userID       int64
-		hashedPass   string
-		roleID       int64
-		email        string
-		phone        string
-	)
-	err := db.QueryRowContext(c.Request().Context(),
-		`SELECT id, password_hash, role_id, email, phone FROM user WHERE email = ? OR phone = ?`,
-		req.Email, req.Email,
-	).Scan(&userID, &hashedPass, &roleID, &email, &phone)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid credentials"})
-		}
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "email or phone is required"})
+	}
+
+	userID, hashedPass, err := findLoginUser(c.Request().Context(), db, req.Email)
+	if err != nil && err != sql.ErrNoRows {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
 	}
 
+	// locked accounts and addresses are refused before the password is even looked at
+	ip := c.RealIP()
+	if wait := loginLocked(rdb, loginAccountSubject(userID, req.Email), ip); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+	if err == sql.ErrNoRows {
+		registerLoginFailure(rdb, pub, 0, req.Email, ip)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid credentials"})
+	}
+
+	// accounts created through Google have no password
+	if !hashedPass.Valid || hashedPass.String == "" {
+		registerLoginFailure(rdb, pub, userID, req.Email, ip)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid credentials"})
+	}
+
 	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(hashedPass), []byte(req.Password)); err != nil {
+	if err := bcrypt.CompareHashAndPassword([]byte(hashedPass.String), []byte(req.Password)); err != nil {
+		registerLoginFailure(rdb, pub, userID, req.Email, ip)
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid credentials"})
 	}
 
-	// Fetch role permissions
-	rows, err := db.QueryContext(c.Request().Context(),
-		`SELECT p.name FROM permissions p
-		 JOIN role_permissions rp ON rp.permission_id = p.id
-		 WHERE rp.role_id = ?`, roleID,
-	)
+	clearLoginFailures(rdb, userID)
+
+	// accounts with 2FA get a challenge instead of tokens
+	challenge, err := SecondFactor(c.Request().Context(), db, rdb, userID)
 	if err != nil {
-		log.Println("failed to fetch permissions:", err)
+		log.Println("failed to check 2FA:", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
 	}
-	defer rows.Close()
-
-	perms := []string{}
-	for rows.Next() {
-		var perm string
-		if err := rows.Scan(&perm); err != nil {
-			log.Println("permission scan error:", err)
-			continue
-		}
-		perms = append(perms, perm)
-	}
-
-	// Generate JWT token
-	expiry := time.Now().Add(24 * time.Hour)
-	claims := jwt.MapClaims{
-		"sub":   userID,
-		"email": email,
-		"phone": phone,
-		"role":  roleID,
-		"perms": perms,
-		"exp":   expiry.Unix(),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenStr, err := token.SignedString([]byte(jwtSecret))
+	if challenge != nil {
+		return c.JSON(http.StatusOK, challenge)
+	}
+
+	resp, err := IssueTokens(c, db, rdb, userID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to generate token"})
+		return tokenError(c, err)
 	}
 
-	// Store token in Redis
-	
-	if err := library.SetRedisKeyWithExpiry(rdb, tokenStr, fmt.Sprintf("%d",userID), 24*3600); err != nil {
-		log.Println("redis set error:", err)
+	return c.JSON(http.StatusOK, resp)
+}
+
+// findLoginUser looks identifier up as an email and otherwise as a phone number, which only identifies a user
+// when exactly one account has it
+func findLoginUser(ctx context.Context, db *sql.DB, identifier string) (int64, sql.NullString, error) {
+	var (
+		userID     int64
+		hashedPass sql.NullString
+	)
+	err := db.QueryRowContext(ctx, `SELECT id, password_hash FROM users WHERE email = ?`, identifier).Scan(&userID, &hashedPass)
+	if err != sql.ErrNoRows {
+		return userID, hashedPass, err
 	}
 
-	// Return token
-	resp := UserLoginResponse{
-		Token:     tokenStr,
-		ExpiresAt: expiry,
+	if userID, err = findUserByPhone(ctx, db, identifier); err != nil {
+		return 0, hashedPass, err
 	}
-	return c.JSON(http.StatusOK, resp)
+	err = db.QueryRowContext(ctx, `SELECT password_hash FROM users WHERE id = ?`, userID).Scan(&hashedPass)
+	return userID, hashedPass, err
 }
 
 // UserCreate creates a new user with role and logs its permissions
-func UserCreate(c echo.Context, db *sql.DB) (*User, error) {
+func UserCreate(c echo.Context, db *sql.DB, pub *mq.Publisher) (*User, error) {
 	ctx := c.Request().Context()
 
 	// Parse request body
@@ -134,22 +131,29 @@ func UserCreate(c echo.Context, db *sql.DB) (*User, error) {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
 	}
 
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "email is required")
+	}
+	if err := library.CheckPasswordStrength(req.Password, req.Email); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to hash password")
 	}
 
-	// Get role_id
-	var roleID int64
-	err = db.QueryRowContext(ctx, "SELECT id FROM roles WHERE name = ?", req.RoleName).Scan(&roleID)
+	// Self-service signups always start as customers
+	roleID, err := CustomerRoleID(ctx, db)
 	if err != nil {
-		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid role name")
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "customer role is missing")
 	}
 
 	// Insert user
 	res, err := db.ExecContext(ctx, `
-        INSERT INTO user (email, password_hash, phone, role_id) VALUES (?, ?, ?, ?)`,
+        INSERT INTO users (email, password_hash, phone, role_id) VALUES (?, ?, ?, ?)`,
 		req.Email, string(hashedPassword), req.Phone, roleID,
 	)
 	if err != nil {
@@ -162,26 +166,13 @@ func UserCreate(c echo.Context, db *sql.DB) (*User, error) {
 	}
 
 	// Fetch permissions
-	rows, err := db.QueryContext(ctx, `
-        SELECT p.name 
-        FROM permissions p
-        JOIN role_permissions rp ON rp.permission_id = p.id
-        WHERE rp.role_id = ?`, roleID)
+	perms, err := rolePermissions(ctx, db, roleID)
 	if err != nil {
 		return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
-	defer rows.Close()
-
-	var perms []string
-	for rows.Next() {
-		var perm string
-		if err := rows.Scan(&perm); err != nil {
-			return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
-		}
-		perms = append(perms, perm)
-	}
 
-	log.Printf("Created user %s with role %s and permissions: %v", req.Email, req.RoleName, perms)
+	log.Printf("Created user %s with role customer and permissions: %v", req.Email, perms)
+	publishUserCreated(ctx, db, pub, userID, "password")
 
 	return &User{ID: userID, Email: req.Email, Phone: req.Phone, RoleID: roleID}, nil
 }
@@ -0,0 +1,330 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"savannah-store/auth-service/internal/models"
+	"savannah-store/auth-service/internal/repository"
+	"savannah-store/pkg/mq"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultUsersPerPage = 20
+	maxUsersPerPage     = 100
+)
+
+var userStatusEvents = map[string]string{
+	"disabled": EventUserDisabled,
+	"enabled":  EventUserEnabled,
+	"deleted":  EventUserDeleted,
+}
+
+// ListUsers returns a page of users, filtered by ?role, ?verified, ?status, ?q and ?created_from/?created_to
+func ListUsers(c echo.Context, db *sql.DB) error {
+	filter, httpErr := userFilter(c)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+
+	users, total, err := repository.NewUserRepository(db).ListUsers(c.Request().Context(), filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, models.UserList{Users: users, Page: filter.Page, PerPage: filter.PerPage, Total: total})
+}
+
+func userFilter(c echo.Context) (models.UserFilter, *echo.HTTPError) {
+	filter := models.UserFilter{
+		Role:    c.QueryParam("role"),
+		Status:  c.QueryParam("status"),
+		Query:   strings.TrimSpace(c.QueryParam("q")),
+		Page:    1,
+		PerPage: defaultUsersPerPage,
+	}
+
+	switch filter.Status {
+	case "", "active", "disabled", "deleted", "all":
+	default:
+		return filter, echo.NewHTTPError(http.StatusBadRequest, "status must be active, disabled, deleted or all")
+	}
+	if v := c.QueryParam("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return filter, echo.NewHTTPError(http.StatusBadRequest, "invalid page")
+		}
+		filter.Page = page
+	}
+	if v := c.QueryParam("per_page"); v != "" {
+		perPage, err := strconv.Atoi(v)
+		if err != nil || perPage < 1 {
+			return filter, echo.NewHTTPError(http.StatusBadRequest, "invalid per_page")
+		}
+		if perPage > maxUsersPerPage {
+			perPage = maxUsersPerPage
+		}
+		filter.PerPage = perPage
+	}
+	if v := c.QueryParam("verified"); v != "" {
+		verified, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, echo.NewHTTPError(http.StatusBadRequest, "verified must be true or false")
+		}
+		filter.Verified = &verified
+	}
+
+	for param, dst := range map[string]**time.Time{"created_from": &filter.CreatedFrom, "created_to": &filter.CreatedTo} {
+		v := c.QueryParam(param)
+		if v == "" {
+			continue
+		}
+		t, err := parseDateParam(v)
+		if err != nil {
+			return filter, echo.NewHTTPError(http.StatusBadRequest, param+" must be a date (2006-01-02) or RFC 3339 time")
+		}
+		// a plain date in created_to includes the whole day
+		if param == "created_to" && len(v) == len("2006-01-02") {
+			t = t.AddDate(0, 0, 1)
+		}
+		*dst = &t
+	}
+
+	return filter, nil
+}
+
+func parseDateParam(v string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// GetUser returns a single user, deleted ones included
+func GetUser(c echo.Context, db *sql.DB) error {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+
+	user, err := repository.NewUserRepository(db).FindByID(c.Request().Context(), userID)
+	if err == repository.ErrUserNotFound {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+// UpdateUser lets an admin change the full name and phone of a user
+func UpdateUser(c echo.Context, db *sql.DB) error {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+	return updateProfile(c, db, userID)
+}
+
+// GetMe returns the profile of the logged in user
+func GetMe(c echo.Context, db *sql.DB) error {
+	user, err := repository.NewUserRepository(db).FindByID(c.Request().Context(), c.Get("user_id").(int64))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, user)
+}
+
+// UpdateMe lets the logged in user change their own full name and phone
+func UpdateMe(c echo.Context, db *sql.DB) error {
+	return updateProfile(c, db, c.Get("user_id").(int64))
+}
+
+func updateProfile(c echo.Context, db *sql.DB, userID int64) error {
+	ctx := c.Request().Context()
+	repo := repository.NewUserRepository(db)
+
+	req := new(models.ProfileUpdate)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+	}
+	if req.FullName != nil {
+		name := strings.TrimSpace(*req.FullName)
+		if len(name) > 255 {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "full_name is too long"})
+		}
+		req.FullName = &name
+	}
+	if req.Phone != nil {
+		phone := strings.TrimSpace(*req.Phone)
+		if len(phone) > 50 {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "phone is too long"})
+		}
+		// the phone is a login and OTP identifier, so it has to stay unique
+		if phone != "" {
+			taken, err := repo.PhoneTaken(ctx, phone, userID)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+			}
+			if taken {
+				return c.JSON(http.StatusConflict, echo.Map{"error": "phone is already in use"})
+			}
+		}
+		req.Phone = &phone
+	}
+
+	user, err := repo.FindByID(ctx, userID)
+	if err == nil && user.DeletedAt != nil {
+		err = repository.ErrUserNotFound
+	}
+	if err == repository.ErrUserNotFound {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	if err := repo.UpdateProfile(ctx, userID, *req); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if user, err = repo.FindByID(ctx, userID); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+// ChangeUserRole moves a user to another role. The user is logged out everywhere so a demotion
+// takes effect right away instead of when the access token expires.
+func ChangeUserRole(c echo.Context, db *sql.DB, rdb *redis.Client, pub *mq.Publisher) error {
+	ctx := c.Request().Context()
+	adminID := c.Get("user_id").(int64)
+
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+	if userID == adminID {
+		return c.JSON(http.StatusForbidden, echo.Map{"error": "you can not change your own role"})
+	}
+
+	req := new(models.RoleChangeRequest)
+	if err := c.Bind(req); err != nil || req.Role == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "role is required"})
+	}
+	roleID, err := roleIDByName(ctx, db, req.Role)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "unknown role"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	user, err := repository.NewUserRepository(db).FindByID(ctx, userID)
+	if err == nil && user.DeletedAt != nil {
+		err = repository.ErrUserNotFound
+	}
+	if err == repository.ErrUserNotFound {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer tx.Rollback()
+
+	event, err := changeUserRole(ctx, tx, userID, roleID, adminID, RoleChangeSourceAdmin)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if event == nil {
+		return c.JSON(http.StatusOK, echo.Map{"message": "user already has role " + req.Role})
+	}
+
+	if err := RevokeUserSessions(rdb, userID); err != nil {
+		log.Println("failed to revoke sessions after role change:", err)
+	}
+	publishRoleChange(pub, event)
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "role changed to " + req.Role})
+}
+
+// DisableUser blocks every login of a user and ends the sessions they have
+func DisableUser(c echo.Context, db *sql.DB, rdb *redis.Client, pub *mq.Publisher) error {
+	return setUserStatus(c, db, rdb, pub, "disabled")
+}
+
+// EnableUser lets a disabled user sign in again
+func EnableUser(c echo.Context, db *sql.DB, rdb *redis.Client, pub *mq.Publisher) error {
+	return setUserStatus(c, db, rdb, pub, "enabled")
+}
+
+// DeleteUser soft deletes a user: the account can never sign in again but the row stays for order history
+func DeleteUser(c echo.Context, db *sql.DB, rdb *redis.Client, pub *mq.Publisher) error {
+	return setUserStatus(c, db, rdb, pub, "deleted")
+}
+
+func setUserStatus(c echo.Context, db *sql.DB, rdb *redis.Client, pub *mq.Publisher, status string) error {
+	ctx := c.Request().Context()
+	repo := repository.NewUserRepository(db)
+
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+	if userID == c.Get("user_id").(int64) {
+		return c.JSON(http.StatusForbidden, echo.Map{"error": "you can not " + strings.TrimSuffix(status, "d") + " your own account"})
+	}
+
+	switch status {
+	case "disabled":
+		err = repo.SetDisabled(ctx, userID, true)
+	case "enabled":
+		err = repo.SetDisabled(ctx, userID, false)
+	case "deleted":
+		err = repo.SoftDelete(ctx, userID)
+	}
+	if err == repository.ErrUserNotFound {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	if status != "enabled" {
+		if err := RevokeUserSessions(rdb, userID); err != nil {
+			log.Println("failed to revoke sessions of", status, "user:", err)
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to revoke sessions"})
+		}
+	}
+
+	adminID := c.Get("user_id").(int64)
+	log.Printf("user %d %s by admin %d", userID, status, adminID)
+
+	if user, err := repo.FindByID(ctx, userID); err != nil {
+		log.Println("failed to load user for status event:", err)
+	} else {
+		publishEvent(pub, userStatusEvents[status], models.UserStatusEvent{
+			UserID:    userID,
+			Email:     user.Email,
+			ChangedBy: adminID,
+			ChangedAt: time.Now().UTC(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "user " + status})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"savannah-store/auth-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ClientToken godoc
+// @Summary      Client credentials grant
+// @Description  OAuth2 client credentials grant (RFC 6749 section 4.4) for machine clients. Credentials go in HTTP Basic auth or the form; scope narrows the granted scopes. The access token carries the scopes as perms and is accepted by the catalog and order services.
+// @Tags         Clients
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type formData string true "client_credentials"
+// @Param        client_id formData string false "Client ID, unless sent with Basic auth"
+// @Param        client_secret formData string false "Client secret, unless sent with Basic auth"
+// @Param        scope formData string false "Space separated subset of the client's scopes"
+// @Success      200  {object} models.ClientTokenResponse
+// @Failure      400  {object} map[string]string "invalid_request, unsupported_grant_type or invalid_scope"
+// @Failure      401  {object} map[string]string "invalid_client"
+// @Router       /auth/token [post]
+func (a *App) ClientToken(c echo.Context) error {
+	return controllers.ClientToken(c, a.DB, a.RedisConnection)
+}
+
+// CreateClient godoc
+// @Summary      Register a machine client
+// @Description  Scopes are module:action permissions. The client_secret is only returned here and on rotation.
+// @Tags         Clients
+// @Accept       json
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        body body models.ClientRequest true "Name and scopes"
+// @Success      201  {object} models.OAuthClient
+// @Failure      400  {object} map[string]string "unknown scope"
+// @Router       /auth/admin/clients [post]
+func (a *App) CreateClient(c echo.Context) error {
+	return controllers.CreateClient(c, a.DB)
+}
+
+// ListClients godoc
+// @Summary      List machine clients
+// @Tags         Clients
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Success      200  {array} models.OAuthClient
+// @Router       /auth/admin/clients [get]
+func (a *App) ListClients(c echo.Context) error {
+	return controllers.ListClients(c, a.DB)
+}
+
+// UpdateClient godoc
+// @Summary      Change the name and scopes of a client
+// @Description  Tokens already issued to the client are revoked
+// @Tags         Clients
+// @Accept       json
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        client_id path string true "Client ID"
+// @Param        body body models.ClientRequest true "Name and scopes"
+// @Success      200  {object} models.OAuthClient
+// @Failure      404  {object} map[string]string
+// @Router       /auth/admin/clients/{client_id} [put]
+func (a *App) UpdateClient(c echo.Context) error {
+	return controllers.UpdateClient(c, a.DB, a.RedisConnection)
+}
+
+// RotateClientSecret godoc
+// @Summary      Rotate the secret of a client
+// @Description  Returns a new secret. The old ones keep working for CLIENT_SECRET_GRACE_PERIOD, or stop at once with immediate=true.
+// @Tags         Clients
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        client_id path string true "Client ID"
+// @Param        immediate query bool false "Expire the old secrets and their tokens now"
+// @Success      200  {object} models.OAuthClient
+// @Failure      404  {object} map[string]string
+// @Router       /auth/admin/clients/{client_id}/rotate [post]
+func (a *App) RotateClientSecret(c echo.Context) error {
+	return controllers.RotateClientSecret(c, a.DB, a.RedisConnection)
+}
+
+// DisableClient godoc
+// @Summary      Disable a client
+// @Description  The client can not get tokens anymore and the ones it has are revoked
+// @Tags         Clients
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        client_id path string true "Client ID"
+// @Success      200  {object} map[string]string
+// @Failure      404  {object} map[string]string
+// @Router       /auth/admin/clients/{client_id} [delete]
+func (a *App) DisableClient(c echo.Context) error {
+	return controllers.DisableClient(c, a.DB, a.RedisConnection)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"savannah-store/auth-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// StartImpersonation godoc
+// @Summary      Impersonate a user
+// @Description  Issues a short lived token acting as the user, with an act claim naming the admin. It has no refresh token, is refused by auth-service itself and every request made with it is audited. Users holding permissions the admin lacks can not be impersonated.
+// @Tags         Impersonation
+// @Accept       json
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id path int true "User ID"
+// @Param        body body models.ImpersonationRequest true "Why the user is impersonated"
+// @Success      201  {object} models.Impersonation
+// @Failure      400  {object} map[string]string
+// @Failure      403  {object} map[string]string "disabled user or permissions the admin lacks"
+// @Failure      404  {object} map[string]string
+// @Router       /auth/admin/users/{id}/impersonate [post]
+func (a *App) StartImpersonation(c echo.Context) error {
+	return controllers.StartImpersonation(c, a.DB, a.RedisConnection)
+}
+
+// ListImpersonations godoc
+// @Summary      List impersonations
+// @Tags         Impersonation
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        user_id query int false "Impersonated user"
+// @Param        admin_id query int false "Impersonating admin"
+// @Param        active query bool false "Only impersonations that have not ended or expired"
+// @Success      200  {array} models.Impersonation
+// @Router       /auth/admin/impersonations [get]
+func (a *App) ListImpersonations(c echo.Context) error {
+	return controllers.ListImpersonations(c, a.DB)
+}
+
+// ImpersonationAudit godoc
+// @Summary      Audit trail of an impersonation
+// @Description  Every request served with the impersonation token, across services, plus its start and end
+// @Tags         Impersonation
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id path string true "Impersonation ID"
+// @Success      200  {array} models.ImpersonationAuditEntry
+// @Router       /auth/admin/impersonations/{id}/audit [get]
+func (a *App) ImpersonationAudit(c echo.Context) error {
+	return controllers.ImpersonationAudit(c, a.DB)
+}
+
+// EndImpersonation godoc
+// @Summary      End an impersonation
+// @Description  Revokes the impersonation token before it expires
+// @Tags         Impersonation
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id path string true "Impersonation ID"
+// @Success      200  {object} map[string]string
+// @Failure      404  {object} map[string]string
+// @Router       /auth/admin/impersonations/{id} [delete]
+func (a *App) EndImpersonation(c echo.Context) error {
+	return controllers.EndImpersonation(c, a.DB, a.RedisConnection)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"savannah-store/auth-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Introspect godoc
+// @Summary      Introspect an access token
+// @Description  Service to service endpoint telling whether a token is active (valid signature, not expired, not revoked) and returning its claims
+// @Tags         Internal
+// @Accept       json
+// @Produce      json
+// @Param        X-Internal-Token header string true "Shared internal API token"
+// @Param        body  body  controllers.IntrospectRequest  true  "Token to introspect"
+// @Success      200  {object} map[string]interface{} "active plus the token claims"
+// @Failure      401  {object} map[string]string
+// @Router       /internal/introspect [post]
+func (a *App) Introspect(c echo.Context) error {
+	return controllers.Introspect(c, a.RedisConnection)
+}
+
+// LookupUser godoc
+// @Summary      Look up a user
+// @Description  Service to service endpoint returning the contact details and role of a user
+// @Tags         Internal
+// @Produce      json
+// @Param        X-Internal-Token header string true "Shared internal API token"
+// @Param        id  path  int  true  "User ID"
+// @Success      200  {object} models.UserProfile
+// @Failure      404  {object} map[string]string
+// @Router       /internal/users/{id} [get]
+func (a *App) LookupUser(c echo.Context) error {
+	return controllers.LookupUser(c, a.DB)
+}
+
+// LookupUsersByRole godoc
+// @Summary      Look up users by role
+// @Description  Service to service endpoint returning every user holding a role, e.g. the admins to notify about orders
+// @Tags         Internal
+// @Produce      json
+// @Param        X-Internal-Token header string true "Shared internal API token"
+// @Param        role  query  string  true  "Role name"
+// @Success      200  {array} models.UserProfile
+// @Router       /internal/users [get]
+func (a *App) LookupUsersByRole(c echo.Context) error {
+	return controllers.LookupUsersByRole(c, a.DB)
+}
+
+// RecordImpersonationAudit godoc
+// @Summary      Record an impersonated action
+// @Description  Service to service endpoint the catalog and order services call for every request served with an impersonation token
+// @Tags         Internal
+// @Accept       json
+// @Produce      json
+// @Param        X-Internal-Token header string true "Shared internal API token"
+// @Param        body  body  models.ImpersonationAuditEntry  true  "The action"
+// @Success      201  {object} map[string]string
+// @Failure      400  {object} map[string]string
+// @Router       /internal/audit/impersonation [post]
+func (a *App) RecordImpersonationAudit(c echo.Context) error {
+	return controllers.RecordImpersonationAudit(c, a.DB)
+}
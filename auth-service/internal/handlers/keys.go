@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"savannah-store/auth-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// JWKS godoc
+// @Summary      JSON Web Key Set
+// @Description  Public keys used to verify tokens issued by auth-service, looked up by the kid header
+// @Tags         Keys
+// @Produce      json
+// @Success      200  {object} map[string]interface{} "keys"
+// @Router       /.well-known/jwks.json [get]
+func (a *App) JWKS(c echo.Context) error {
+	return controllers.JWKS(c)
+}
+
+// ListSigningKeys godoc
+// @Summary      List signing keys
+// @Description  Lists the active signing key and the retired keys that are still accepted
+// @Tags         Keys
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Success      200  {array} controllers.SigningKeyResponse
+// @Router       /auth/admin/keys [get]
+func (a *App) ListSigningKeys(c echo.Context) error {
+	return controllers.ListSigningKeys(c)
+}
+
+// RotateSigningKey godoc
+// @Summary      Rotate signing key
+// @Description  Generates a new signing key. The previous key stays published for JWT_KEY_OVERLAP seconds so issued tokens keep working.
+// @Tags         Keys
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Success      201  {object} controllers.SigningKeyResponse
+// @Router       /auth/admin/keys/rotate [post]
+func (a *App) RotateSigningKey(c echo.Context) error {
+	return controllers.RotateSigningKey(c)
+}
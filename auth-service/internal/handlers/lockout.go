@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"savannah-store/auth-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// UnlockUser godoc
+// @Summary Unlock a locked out user
+// @Description Clears the failed login count and lockout of the user, and of a client IP when ip is given. Published as user.unlocked
+// @Tags Admin
+// @Produce json
+// @Param api-key header string true "API Key for authentication"
+// @Param id path int true "User ID"
+// @Param ip query string false "Client IP to unlock as well"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string "invalid user id"
+// @Router /auth/admin/users/{id}/unlock [post]
+func (a *App) UnlockUser(c echo.Context) error {
+	return controllers.UnlockUser(c, a.RedisConnection, a.Publisher)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"savannah-store/auth-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// VerifyMFA godoc
+// @Summary Complete a 2FA login
+// @Description Answers the challenge returned by a login (mfa_required) with a TOTP or recovery code and returns the tokens
+// @Tags 2FA
+// @Accept json
+// @Produce json
+// @Param body body models.MFACodeRequest true "Challenge and code"
+// @Success 200 {object} controllers.UserLoginResponse
+// @Failure 401 {object} map[string]string "invalid code"
+// @Failure 429 {object} map[string]string "too many attempts"
+// @Router /auth/2fa/verify [post]
+func (a *App) VerifyMFA(c echo.Context) error {
+	return controllers.VerifyMFA(c, a.DB, a.RedisConnection)
+}
+
+// EnrollMFA godoc
+// @Summary Start 2FA enrollment
+// @Description Creates a TOTP secret and returns its otpauth:// provisioning URI to show as a QR code. Call with an api-key, or with the challenge of a login that returned mfa_enrollment_required.
+// @Tags 2FA
+// @Accept json
+// @Produce json
+// @Param api-key header string false "API Key for authentication"
+// @Param body body models.MFAEnrollRequest false "Enrollment challenge"
+// @Success 200 {object} models.MFAEnrollment
+// @Failure 409 {object} map[string]string "2FA already enabled"
+// @Router /auth/2fa/enroll [post]
+func (a *App) EnrollMFA(c echo.Context) error {
+	return controllers.EnrollMFA(c, a.DB, a.RedisConnection)
+}
+
+// ActivateMFA godoc
+// @Summary Confirm 2FA enrollment
+// @Description Enables 2FA once the first TOTP code checks out and returns the recovery codes. During a login enrollment the tokens are returned too.
+// @Tags 2FA
+// @Accept json
+// @Produce json
+// @Param api-key header string false "API Key for authentication"
+// @Param body body models.MFACodeRequest true "Code and, during login, the challenge"
+// @Success 200 {object} map[string]interface{} "recovery_codes"
+// @Failure 401 {object} map[string]string "invalid code"
+// @Router /auth/2fa/activate [post]
+func (a *App) ActivateMFA(c echo.Context) error {
+	return controllers.ActivateMFA(c, a.DB, a.RedisConnection)
+}
+
+// DisableMFA godoc
+// @Summary Disable 2FA
+// @Description Not allowed when the 2FA policy makes it mandatory for the user's role
+// @Tags 2FA
+// @Accept json
+// @Produce json
+// @Param api-key header string true "API Key for authentication"
+// @Param body body models.MFACodeRequest true "TOTP or recovery code"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string "2FA is mandatory"
+// @Router /auth/2fa/disable [post]
+func (a *App) DisableMFA(c echo.Context) error {
+	return controllers.DisableMFA(c, a.DB)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Replace the recovery codes
+// @Tags 2FA
+// @Accept json
+// @Produce json
+// @Param api-key header string true "API Key for authentication"
+// @Param body body models.MFACodeRequest true "TOTP code"
+// @Success 200 {object} map[string]interface{} "recovery_codes"
+// @Failure 401 {object} map[string]string "invalid code"
+// @Router /auth/2fa/recovery-codes [post]
+func (a *App) RegenerateRecoveryCodes(c echo.Context) error {
+	return controllers.RegenerateRecoveryCodes(c, a.DB)
+}
+
+// ResetUserMFA godoc
+// @Summary Reset the 2FA of a user
+// @Description For users who lost their authenticator and recovery codes. Published as user.mfa_reset
+// @Tags 2FA
+// @Produce json
+// @Param api-key header string true "API Key for authentication"
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Router /auth/admin/users/{id}/2fa [delete]
+func (a *App) ResetUserMFA(c echo.Context) error {
+	return controllers.ResetUserMFA(c, a.DB, a.Publisher)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"savannah-store/auth-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RequestLoginOTP godoc
+// @Summary Ask for a one time login code
+// @Description Sends a 6 digit code by email (when email is given) or SMS (when phone is given). The answer is the same whether or not the account exists.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.OTPRequest true "Email or phone"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string "email or phone is required"
+// @Router /auth/otp/request [post]
+func (a *App) RequestLoginOTP(c echo.Context) error {
+	return controllers.RequestLoginOTP(c, a.DB, a.RedisConnection, a.RabbitMQConn)
+}
+
+// LoginWithOTP godoc
+// @Summary Log in with a one time code
+// @Description Exchanges the code for an access and refresh token and marks the email or phone it was sent to as verified. Codes expire after OTP_TTL and are burnt after OTP_MAX_ATTEMPTS wrong guesses.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.OTPLoginRequest true "Email or phone and code"
+// @Success 200 {object} controllers.UserLoginResponse
+// @Failure 400 {object} map[string]string "invalid or expired code"
+// @Failure 429 {object} map[string]string "too many wrong codes"
+// @Router /auth/otp/verify [post]
+func (a *App) LoginWithOTP(c echo.Context) error {
+	return controllers.LoginWithOTP(c, a.DB, a.RedisConnection, a.Publisher)
+}
+
+// RequestVerification godoc
+// @Summary Ask for a verification code
+// @Description Sends a code to the email ("email") or phone ("sms") of the calling user
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param api-key header string true "API Key for authentication"
+// @Param body body models.VerificationRequest true "Channel"
+// @Success 200 {object} map[string]string
+// @Failure 409 {object} map[string]string "already verified"
+// @Failure 429 {object} map[string]string "a code was sent recently"
+// @Router /auth/verify/request [post]
+func (a *App) RequestVerification(c echo.Context) error {
+	return controllers.RequestVerification(c, a.DB, a.RedisConnection, a.RabbitMQConn)
+}
+
+// ConfirmVerification godoc
+// @Summary Confirm a verification code
+// @Description Marks the email or phone the code was sent to as verified and returns a new access and refresh token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param api-key header string true "API Key for authentication"
+// @Param body body models.VerificationConfirmRequest true "Code"
+// @Success 200 {object} controllers.UserLoginResponse
+// @Failure 400 {object} map[string]string "invalid or expired code"
+// @Failure 429 {object} map[string]string "too many wrong codes"
+// @Router /auth/verify/confirm [post]
+func (a *App) ConfirmVerification(c echo.Context) error {
+	return controllers.ConfirmVerification(c, a.DB, a.RedisConnection, a.Publisher)
+}
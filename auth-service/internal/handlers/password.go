@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"savannah-store/auth-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ForgotPassword godoc
+// @Summary Ask for a password reset link
+// @Description Emails a single use reset link that expires after PASSWORD_RESET_TTL. The answer is the same whether or not the account exists.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.ForgotPasswordRequest true "Account email"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string "email is required"
+// @Router /auth/password/forgot [post]
+func (a *App) ForgotPassword(c echo.Context) error {
+	return controllers.ForgotPassword(c, a.DB, a.RedisConnection, a.RabbitMQConn)
+}
+
+// ResetPassword godoc
+// @Summary Reset the password
+// @Description Sets a new password with the token from the reset link and signs the account out everywhere
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string "invalid or expired token, or weak password"
+// @Router /auth/password/reset [post]
+func (a *App) ResetPassword(c echo.Context) error {
+	return controllers.ResetPassword(c, a.DB, a.RedisConnection, a.RabbitMQConn, a.Publisher)
+}
+
+// ChangePassword godoc
+// @Summary Change the password
+// @Description Replaces the password of the calling user, signs out every other session and returns a new token pair
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param api-key header string true "API Key for authentication"
+// @Param body body models.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} controllers.UserLoginResponse
+// @Failure 400 {object} map[string]string "weak password"
+// @Failure 401 {object} map[string]string "current password is wrong"
+// @Router /auth/password/change [post]
+func (a *App) ChangePassword(c echo.Context) error {
+	return controllers.ChangePassword(c, a.DB, a.RedisConnection, a.RabbitMQConn)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"savannah-store/auth-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ExportMyData godoc
+// @Summary      Download my personal data
+// @Description  Everything held about the calling user in every service: account, identities, sessions and role history from auth-service, orders and cart from order-service, notifications from notification-service. A zip with one JSON file per service, or one JSON document with format=json.
+// @Tags         Personal data
+// @Produce      application/zip
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        format query string false "zip (default) or json"
+// @Success      200  {object} models.PersonalDataExport
+// @Failure      502  {object} map[string]string "a service could not be reached"
+// @Router       /auth/me/export [get]
+func (a *App) ExportMyData(c echo.Context) error {
+	return controllers.ExportMyData(c, a.DB, a.RedisConnection)
+}
+
+// ExportUserData godoc
+// @Summary      Download the personal data of a user
+// @Description  The export of /auth/me/export for the user :id, to answer a data subject request
+// @Tags         Personal data
+// @Produce      application/zip
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id path int true "User ID"
+// @Param        format query string false "zip (default) or json"
+// @Success      200  {object} models.PersonalDataExport
+// @Failure      404  {object} map[string]string
+// @Failure      502  {object} map[string]string "a service could not be reached"
+// @Router       /auth/admin/users/{id}/export [get]
+func (a *App) ExportUserData(c echo.Context) error {
+	return controllers.ExportUserData(c, a.DB, a.RedisConnection)
+}
+
+// RequestErasure godoc
+// @Summary      Erase the personal data of a user
+// @Description  Disables and logs the user out at once and queues an erasure job that anonymises the account, its orders and its notifications in every service. Orders keep their items and totals for accounting. Poll the job with /auth/admin/erasures/{id}.
+// @Tags         Personal data
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id path int true "User ID"
+// @Success      202  {object} models.ErasureJob
+// @Failure      403  {object} map[string]string
+// @Failure      404  {object} map[string]string
+// @Router       /auth/admin/users/{id}/erase [post]
+func (a *App) RequestErasure(c echo.Context) error {
+	return controllers.RequestErasure(c, a.DB, a.RedisConnection)
+}
+
+// GetErasureJob godoc
+// @Summary      Progress of an erasure job
+// @Tags         Personal data
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id path int true "Erasure job ID"
+// @Success      200  {object} models.ErasureJob
+// @Failure      404  {object} map[string]string
+// @Router       /auth/admin/erasures/{id} [get]
+func (a *App) GetErasureJob(c echo.Context) error {
+	return controllers.GetErasureJob(c, a.DB)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"savannah-store/auth-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ListRoles godoc
+// @Summary      List roles
+// @Description  Lists every role together with its "module:action" permissions
+// @Tags         RBAC
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Success      200  {array} models.Role
+// @Failure      403  {object} map[string]string
+// @Router       /auth/admin/roles [get]
+func (a *App) ListRoles(c echo.Context) error {
+	return controllers.ListRoles(c, a.DB)
+}
+
+// CreateRole godoc
+// @Summary      Create a role
+// @Description  Creates a role without any permissions
+// @Tags         RBAC
+// @Accept       json
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        body  body  models.RoleRequest  true  "Role info"
+// @Success      201  {object} models.Role
+// @Failure      400  {object} map[string]string
+// @Failure      409  {object} map[string]string "role already exists"
+// @Router       /auth/admin/roles [post]
+func (a *App) CreateRole(c echo.Context) error {
+	return controllers.CreateRole(c, a.DB)
+}
+
+// GrantPermission godoc
+// @Summary      Grant a permission to a role
+// @Tags         RBAC
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id      path  int     true  "Role ID"
+// @Param        module  path  string  true  "Module name e.g. product"
+// @Param        action  path  string  true  "create, read, update or delete"
+// @Success      200  {object} map[string]string
+// @Failure      404  {object} map[string]string "role or permission not found"
+// @Router       /auth/admin/roles/{id}/permissions/{module}/{action} [put]
+func (a *App) GrantPermission(c echo.Context) error {
+	return controllers.GrantPermission(c, a.DB)
+}
+
+// RevokePermission godoc
+// @Summary      Revoke a permission from a role
+// @Tags         RBAC
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id      path  int     true  "Role ID"
+// @Param        module  path  string  true  "Module name e.g. product"
+// @Param        action  path  string  true  "create, read, update or delete"
+// @Success      200  {object} map[string]string
+// @Failure      404  {object} map[string]string "role or permission not found"
+// @Router       /auth/admin/roles/{id}/permissions/{module}/{action} [delete]
+func (a *App) RevokePermission(c echo.Context) error {
+	return controllers.RevokePermission(c, a.DB)
+}
+
+// ListPermissions godoc
+// @Summary      List permissions
+// @Tags         RBAC
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Success      200  {array} models.Permission
+// @Router       /auth/admin/permissions [get]
+func (a *App) ListPermissions(c echo.Context) error {
+	return controllers.ListPermissions(c, a.DB)
+}
+
+// CreateModule godoc
+// @Summary      Create a module
+// @Description  Registers a module and seeds its create/read/update/delete permissions, all granted to admin
+// @Tags         RBAC
+// @Accept       json
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        body  body  models.ModuleRequest  true  "Module info"
+// @Success      201  {object} map[string]string
+// @Router       /auth/admin/modules [post]
+func (a *App) CreateModule(c echo.Context) error {
+	return controllers.CreateModule(c, a.DB)
+}
+
+// SeedPermissions godoc
+// @Summary      Seed default permissions
+// @Description  Creates any missing create/read/update/delete permission for every module and grants them all to admin
+// @Tags         RBAC
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Success      200  {object} map[string]string
+// @Router       /auth/admin/permissions/seed [post]
+func (a *App) SeedPermissions(c echo.Context) error {
+	return controllers.SeedPermissions(c, a.DB)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"savannah-store/auth-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CreateInvite godoc
+// @Summary      Invite someone into a role
+// @Description  Issues a signed, single use invite granting a role to the account with the invited email. Pass the token to /auth/google/start or /auth/invites/accept.
+// @Tags         Roles
+// @Accept       json
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        body  body  models.InviteRequest  true  "Email and role"
+// @Success      201  {object} models.RoleInvite
+// @Failure      400  {object} map[string]string
+// @Failure      404  {object} map[string]string "role not found"
+// @Router       /auth/admin/invites [post]
+func (a *App) CreateInvite(c echo.Context) error {
+	return controllers.CreateInvite(c, a.DB)
+}
+
+// ListInvites godoc
+// @Summary      List invites
+// @Tags         Roles
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Success      200  {array} models.RoleInvite
+// @Router       /auth/admin/invites [get]
+func (a *App) ListInvites(c echo.Context) error {
+	return controllers.ListInvites(c, a.DB)
+}
+
+// AcceptInvite godoc
+// @Summary      Accept an invite
+// @Description  Moves the calling user to the invited role. The invite must have been issued for the user's verified email.
+// @Tags         Roles
+// @Accept       json
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        body  body  models.AcceptInviteRequest  true  "Invite token"
+// @Success      200  {object} map[string]string
+// @Failure      400  {object} map[string]string "invalid or expired invite"
+// @Failure      403  {object} map[string]string "invite issued for another email"
+// @Failure      409  {object} map[string]string "invite already used"
+// @Router       /auth/invites/accept [post]
+func (a *App) AcceptInvite(c echo.Context) error {
+	return controllers.AcceptInvite(c, a.DB, a.Publisher)
+}
+
+// RequestRole godoc
+// @Summary      Ask for a role
+// @Description  Files a role elevation request for an admin to approve or reject
+// @Tags         Roles
+// @Accept       json
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        body  body  models.ElevationRequest  true  "Requested role and reason"
+// @Success      201  {object} models.RoleElevation
+// @Failure      404  {object} map[string]string "role not found"
+// @Failure      409  {object} map[string]string "role already held or request pending"
+// @Router       /auth/role-requests [post]
+func (a *App) RequestRole(c echo.Context) error {
+	return controllers.RequestRole(c, a.DB)
+}
+
+// ListRoleRequests godoc
+// @Summary      List role requests
+// @Tags         Roles
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        status  query  string  false  "pending (default), approved or rejected"
+// @Success      200  {array} models.RoleElevation
+// @Router       /auth/admin/role-requests [get]
+func (a *App) ListRoleRequests(c echo.Context) error {
+	return controllers.ListRoleRequests(c, a.DB)
+}
+
+// ApproveRoleRequest godoc
+// @Summary      Approve a role request
+// @Description  Gives the user the requested role, records the change and publishes user.role_changed
+// @Tags         Roles
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id  path  int  true  "Request ID"
+// @Success      200  {object} map[string]string
+// @Failure      404  {object} map[string]string "request not found"
+// @Failure      409  {object} map[string]string "request already decided"
+// @Router       /auth/admin/role-requests/{id}/approve [post]
+func (a *App) ApproveRoleRequest(c echo.Context) error {
+	return controllers.ApproveRoleRequest(c, a.DB, a.Publisher)
+}
+
+// RejectRoleRequest godoc
+// @Summary      Reject a role request
+// @Tags         Roles
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id  path  int  true  "Request ID"
+// @Success      200  {object} map[string]string
+// @Failure      404  {object} map[string]string "request not found"
+// @Failure      409  {object} map[string]string "request already decided"
+// @Router       /auth/admin/role-requests/{id}/reject [post]
+func (a *App) RejectRoleRequest(c echo.Context) error {
+	return controllers.RejectRoleRequest(c, a.DB)
+}
@@ -3,9 +3,15 @@ package handlers
 import (
 	"database/sql"
 	"fmt"
+	"savannah-store/auth-service/internal/controllers"
+	"savannah-store/auth-service/internal/library"
 	"savannah-store/auth-service/internal/logger"
 	_ "savannah-store/auth-service/docs"
-	"savannah-store/auth-service/internal/repository"
+	auth "savannah-store/auth-service/internal/middleware"
+	"savannah-store/pkg/authn"
+	"savannah-store/pkg/dbx"
+	"savannah-store/pkg/mq"
+	"savannah-store/pkg/redisx"
 
 	"github.com/go-redis/redis"
 	"github.com/gorilla/sessions"
@@ -20,8 +26,6 @@ import (
 	echoLogger "github.com/mudphilo/echo-logger"
 
 	amqp "github.com/rabbitmq/amqp091-go"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 )
 
 // router and DB instance
@@ -31,32 +35,40 @@ type App struct {
 	E               *echo.Echo
 	RedisConnection *redis.Client
 	RabbitMQConn    *amqp.Connection
-	GoogleOauthConfig *oauth2.Config
+	Publisher       *mq.Publisher
+	Providers       library.Providers
 }
 
 // Initialize initializes the app with predefined configuration
 func (a *App) Initialize() {
 
-	a.RedisConnection = repository.RedisClient()
-	a.RabbitMQConn = repository.GetRabbitMQConnection()
+	a.RedisConnection = redisx.NewClient()
+	a.RabbitMQConn = mq.Dial()
+
+	publisher, err := mq.NewPublisher(a.RabbitMQConn, "user.events", "auth-service")
+	if err != nil {
+		logger.Error("failed to open event publisher %v", err)
+	}
+	a.Publisher = publisher
 	
 
 	dbName := os.Getenv("AUTH_DB_NAME")
-	var googleOauthConfig *oauth2.Config
-	// Google OAuth2 setup
-	googleOauthConfig = &oauth2.Config{
-		ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-		ClientSecret: os.Getenv("GOOGLE_CLIENT_KEY"),
-		RedirectURL:  "https://auth.vaslinkcomm.com/auth/google/callback",
-		Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
-		Endpoint:     google.Endpoint,
+	providers, err := library.LoadProviders()
+	if err != nil {
+		logger.Error("failed to load identity providers %v", err)
 	}
-	a.GoogleOauthConfig = googleOauthConfig
+	a.Providers = providers
 
-	dbO := repository.DbInstance(dbName)
+	dbO := dbx.Open(dbName)
 	a.DB = dbO
+
+	if err := library.InitSigningKeys(a.DB); err != nil {
+		logger.Error("failed to load signing keys %v", err)
+	}
 	
 
+	go controllers.RunErasureJobs(a.DB, a.RedisConnection, a.Publisher)
+
 	a.setRouters()
 
 }
@@ -92,8 +104,100 @@ func (a *App) setRouters() {
 	
 
 	// Routes
-	a.E.POST("/auth/google/start", a.StartGoogleAuth)
-	a.E.GET("/auth/google/callback", a.GoogleCallback)
+	a.E.GET("/auth/providers", a.ListProviders)
+	a.E.POST("/auth/:provider/start", a.StartAuth, auth.OptionalAuthenticated(a.RedisConnection))
+	a.E.GET("/auth/:provider/callback", a.AuthCallback)
+	a.E.GET("/auth/identities", a.ListIdentities, auth.Authenticated(a.RedisConnection))
+	a.E.DELETE("/auth/identities/:id", a.UnlinkIdentity, auth.Authenticated(a.RedisConnection))
+	a.E.POST("/auth/signup", a.UserSignup)
+	a.E.POST("/auth/login", a.UserLogin)
+	a.E.POST("/auth/refresh", a.RefreshToken)
+	a.E.POST("/auth/password/forgot", a.ForgotPassword)
+	a.E.POST("/auth/password/reset", a.ResetPassword)
+	a.E.POST("/auth/password/change", a.ChangePassword, auth.Authenticated(a.RedisConnection))
+	a.E.POST("/auth/otp/request", a.RequestLoginOTP)
+	a.E.POST("/auth/otp/verify", a.LoginWithOTP)
+	a.E.POST("/auth/verify/request", a.RequestVerification, auth.Authenticated(a.RedisConnection))
+	a.E.POST("/auth/verify/confirm", a.ConfirmVerification, auth.Authenticated(a.RedisConnection))
+	a.E.POST("/auth/logout", a.Logout, auth.Authenticated(a.RedisConnection))
+	a.E.POST("/auth/logout/all", a.LogoutAll, auth.Authenticated(a.RedisConnection))
+
+	// RBAC administration
+	a.E.GET("/auth/admin/roles", a.ListRoles, auth.PermissionMiddleware(a.DB, a.RedisConnection, "role", "read"))
+	a.E.POST("/auth/admin/roles", a.CreateRole, auth.PermissionMiddleware(a.DB, a.RedisConnection, "role", "create"))
+	a.E.PUT("/auth/admin/roles/:id/permissions/:module/:action", a.GrantPermission, auth.PermissionMiddleware(a.DB, a.RedisConnection, "role", "update"))
+	a.E.DELETE("/auth/admin/roles/:id/permissions/:module/:action", a.RevokePermission, auth.PermissionMiddleware(a.DB, a.RedisConnection, "role", "update"))
+	a.E.GET("/auth/admin/permissions", a.ListPermissions, auth.PermissionMiddleware(a.DB, a.RedisConnection, "role", "read"))
+	a.E.POST("/auth/admin/permissions/seed", a.SeedPermissions, auth.PermissionMiddleware(a.DB, a.RedisConnection, "role", "update"))
+	a.E.POST("/auth/admin/modules", a.CreateModule, auth.PermissionMiddleware(a.DB, a.RedisConnection, "role", "create"))
+
+	// two factor authentication
+	a.E.POST("/auth/2fa/verify", a.VerifyMFA)
+	a.E.POST("/auth/2fa/enroll", a.EnrollMFA, auth.OptionalAuthenticated(a.RedisConnection))
+	a.E.POST("/auth/2fa/activate", a.ActivateMFA, auth.OptionalAuthenticated(a.RedisConnection))
+	a.E.POST("/auth/2fa/disable", a.DisableMFA, auth.Authenticated(a.RedisConnection))
+	a.E.POST("/auth/2fa/recovery-codes", a.RegenerateRecoveryCodes, auth.Authenticated(a.RedisConnection))
+	a.E.DELETE("/auth/admin/users/:id/2fa", a.ResetUserMFA, auth.PermissionMiddleware(a.DB, a.RedisConnection, "user", "update"))
+
+	// user management
+	a.E.GET("/auth/me", a.GetMe, auth.Authenticated(a.RedisConnection))
+	a.E.PATCH("/auth/me", a.UpdateMe, auth.Authenticated(a.RedisConnection))
+	a.E.GET("/auth/admin/users", a.ListUsers, auth.PermissionMiddleware(a.DB, a.RedisConnection, "user", "read"))
+	a.E.GET("/auth/admin/users/:id", a.GetUser, auth.PermissionMiddleware(a.DB, a.RedisConnection, "user", "read"))
+	a.E.PATCH("/auth/admin/users/:id", a.UpdateUser, auth.PermissionMiddleware(a.DB, a.RedisConnection, "user", "update"))
+	a.E.PUT("/auth/admin/users/:id/role", a.ChangeUserRole, auth.PermissionMiddleware(a.DB, a.RedisConnection, "role", "update"))
+	a.E.POST("/auth/admin/users/:id/disable", a.DisableUser, auth.PermissionMiddleware(a.DB, a.RedisConnection, "user", "update"))
+	a.E.POST("/auth/admin/users/:id/enable", a.EnableUser, auth.PermissionMiddleware(a.DB, a.RedisConnection, "user", "update"))
+	a.E.DELETE("/auth/admin/users/:id", a.DeleteUser, auth.PermissionMiddleware(a.DB, a.RedisConnection, "user", "delete"))
+
+	// sessions
+	a.E.GET("/auth/sessions", a.ListSessions, auth.Authenticated(a.RedisConnection))
+	a.E.DELETE("/auth/sessions/:id", a.DeleteSession, auth.Authenticated(a.RedisConnection))
+	a.E.GET("/auth/admin/users/:id/sessions", a.ListUserSessions, auth.PermissionMiddleware(a.DB, a.RedisConnection, "user", "read"))
+	a.E.DELETE("/auth/admin/users/:id/sessions/:sid", a.DeleteUserSession, auth.PermissionMiddleware(a.DB, a.RedisConnection, "user", "update"))
+
+	// impersonation
+	a.E.POST("/auth/admin/users/:id/impersonate", a.StartImpersonation, auth.PermissionMiddleware(a.DB, a.RedisConnection, "impersonation", "create"))
+	a.E.GET("/auth/admin/impersonations", a.ListImpersonations, auth.PermissionMiddleware(a.DB, a.RedisConnection, "impersonation", "read"))
+	a.E.GET("/auth/admin/impersonations/:id/audit", a.ImpersonationAudit, auth.PermissionMiddleware(a.DB, a.RedisConnection, "impersonation", "read"))
+	a.E.DELETE("/auth/admin/impersonations/:id", a.EndImpersonation, auth.PermissionMiddleware(a.DB, a.RedisConnection, "impersonation", "delete"))
+
+	// personal data export and erasure
+	a.E.GET("/auth/me/export", a.ExportMyData, auth.Authenticated(a.RedisConnection))
+	a.E.GET("/auth/admin/users/:id/export", a.ExportUserData, auth.PermissionMiddleware(a.DB, a.RedisConnection, "user", "read"))
+	a.E.POST("/auth/admin/users/:id/erase", a.RequestErasure, auth.PermissionMiddleware(a.DB, a.RedisConnection, "user", "delete"))
+	a.E.GET("/auth/admin/erasures/:id", a.GetErasureJob, auth.PermissionMiddleware(a.DB, a.RedisConnection, "user", "read"))
+
+	// login lockout
+	a.E.POST("/auth/admin/users/:id/unlock", a.UnlockUser, auth.PermissionMiddleware(a.DB, a.RedisConnection, "user", "update"))
+
+	// role invitations and elevation requests
+	a.E.POST("/auth/invites/accept", a.AcceptInvite, auth.Authenticated(a.RedisConnection))
+	a.E.POST("/auth/role-requests", a.RequestRole, auth.Authenticated(a.RedisConnection))
+	a.E.GET("/auth/admin/invites", a.ListInvites, auth.PermissionMiddleware(a.DB, a.RedisConnection, "role", "read"))
+	a.E.POST("/auth/admin/invites", a.CreateInvite, auth.PermissionMiddleware(a.DB, a.RedisConnection, "role", "update"))
+	a.E.GET("/auth/admin/role-requests", a.ListRoleRequests, auth.PermissionMiddleware(a.DB, a.RedisConnection, "role", "read"))
+	a.E.POST("/auth/admin/role-requests/:id/approve", a.ApproveRoleRequest, auth.PermissionMiddleware(a.DB, a.RedisConnection, "role", "update"))
+	a.E.POST("/auth/admin/role-requests/:id/reject", a.RejectRoleRequest, auth.PermissionMiddleware(a.DB, a.RedisConnection, "role", "update"))
+
+	// machine clients, client credentials grant
+	a.E.POST("/auth/token", a.ClientToken)
+	a.E.GET("/auth/admin/clients", a.ListClients, auth.PermissionMiddleware(a.DB, a.RedisConnection, "client", "read"))
+	a.E.POST("/auth/admin/clients", a.CreateClient, auth.PermissionMiddleware(a.DB, a.RedisConnection, "client", "create"))
+	a.E.PUT("/auth/admin/clients/:client_id", a.UpdateClient, auth.PermissionMiddleware(a.DB, a.RedisConnection, "client", "update"))
+	a.E.POST("/auth/admin/clients/:client_id/rotate", a.RotateClientSecret, auth.PermissionMiddleware(a.DB, a.RedisConnection, "client", "update"))
+	a.E.DELETE("/auth/admin/clients/:client_id", a.DisableClient, auth.PermissionMiddleware(a.DB, a.RedisConnection, "client", "delete"))
+
+	// signing keys
+	a.E.GET("/.well-known/jwks.json", a.JWKS)
+	a.E.GET("/auth/admin/keys", a.ListSigningKeys, auth.PermissionMiddleware(a.DB, a.RedisConnection, "key", "read"))
+	a.E.POST("/auth/admin/keys/rotate", a.RotateSigningKey, auth.PermissionMiddleware(a.DB, a.RedisConnection, "key", "update"))
+
+	// service to service
+	a.E.POST("/internal/introspect", a.Introspect, authn.InternalMiddleware())
+	a.E.GET("/internal/users", a.LookupUsersByRole, authn.InternalMiddleware())
+	a.E.GET("/internal/users/:id", a.LookupUser, authn.InternalMiddleware())
+	a.E.POST("/internal/audit/impersonation", a.RecordImpersonationAudit, authn.InternalMiddleware())
 	
 
 	//status
@@ -104,7 +208,7 @@ func (a *App) setRouters() {
 
 func (a *App) GetStatus(c echo.Context) error {
 
-	return c.JSON(repository.CheckConnectionStatus())
+	return c.JSON(dbx.CheckConnectionStatus())
 
 }
 
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"savannah-store/auth-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ListSessions godoc
+// @Summary      List my sessions
+// @Description  Every login is a session with its device, IP, user agent and last refresh; the calling session is marked current
+// @Tags         Sessions
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Success      200  {array} models.Session
+// @Router       /auth/sessions [get]
+func (a *App) ListSessions(c echo.Context) error {
+	return controllers.ListSessions(c, a.RedisConnection)
+}
+
+// DeleteSession godoc
+// @Summary      Log one of my sessions out
+// @Description  Revokes the refresh token and the access tokens of the session
+// @Tags         Sessions
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id path string true "Session ID"
+// @Success      200  {object} map[string]string
+// @Failure      404  {object} map[string]string
+// @Router       /auth/sessions/{id} [delete]
+func (a *App) DeleteSession(c echo.Context) error {
+	return controllers.DeleteSession(c, a.RedisConnection)
+}
+
+// ListUserSessions godoc
+// @Summary      List the sessions of a user
+// @Tags         Sessions
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id path int true "User ID"
+// @Success      200  {array} models.Session
+// @Router       /auth/admin/users/{id}/sessions [get]
+func (a *App) ListUserSessions(c echo.Context) error {
+	return controllers.ListUserSessions(c, a.RedisConnection)
+}
+
+// DeleteUserSession godoc
+// @Summary      Log a session of a user out
+// @Tags         Sessions
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id path int true "User ID"
+// @Param        sid path string true "Session ID"
+// @Success      200  {object} map[string]string
+// @Failure      404  {object} map[string]string
+// @Router       /auth/admin/users/{id}/sessions/{sid} [delete]
+func (a *App) DeleteUserSession(c echo.Context) error {
+	return controllers.DeleteUserSession(c, a.RedisConnection)
+}
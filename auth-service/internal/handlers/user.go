@@ -1,70 +1,97 @@
 package handlers
 
 import (
-	"context"
-	"database/sql"
-	"encoding/json"
-	"fmt"
+	"log"
 	"net/http"
-	"os"
 	"savannah-store/auth-service/internal/controllers"
-	"savannah-store/auth-service/internal/library"
-	"savannah-store/auth-service/internal/models"
-	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
-	"golang.org/x/oauth2"
 )
 
-// StartGoogleAuth godoc
-// @Summary Start Google OAuth flow
-// @Description Initiates Google OAuth by generating a URL and optional state for signup.
+// ListProviders godoc
+// @Summary List identity providers
+// @Description Lists the names of the configured identity providers usable with /auth/{provider}/start
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} map[string][]string "providers"
+// @Router /auth/providers [get]
+func (a *App) ListProviders(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{"providers": a.Providers.Names()})
+}
+
+// StartAuth godoc
+// @Summary Start an identity provider login
+// @Description Returns the login URL of the provider (google, microsoft, github or a configured OIDC issuer) and a single use state. New accounts are always customers; pass an invite to be given the invited role. Called with an api-key, the provider identity is linked to the calling user instead; the link is bound to the browser through an HttpOnly oauth_link cookie that the callback checks.
 // @Tags Auth
 // @Accept json
 // @Produce json
-// @Param request body models.AuthRequest false "Optional phone and usertype"
-// @Success 200 {object} map[string]interface{} "google_url and state"
-// @Failure 500 {object} map[string]string "failed to store usertype or phone"
-// @Router /auth/google/start [post]
-func (a *App) StartGoogleAuth(c echo.Context) error {
-	var req struct {
-		Phone    string `json:"phone"`
-		Usertype string `json:"usertype"`
+// @Param provider path string true "Provider name e.g. google"
+// @Param api-key header string false "Link the identity to this user"
+// @Param request body models.AuthRequest false "Optional phone and invite"
+// @Success 200 {object} map[string]interface{} "auth_url and state"
+// @Failure 404 {object} map[string]string "unknown provider"
+// @Failure 500 {object} map[string]string "failed to store state"
+// @Router /auth/{provider}/start [post]
+func (a *App) StartAuth(c echo.Context) error {
+	provider, ok := a.Providers[c.Param("provider")]
+	if !ok {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "unknown provider"})
 	}
+	return controllers.StartProviderAuth(c, a.RedisConnection, provider)
+}
 
-	_ = c.Bind(&req) // ignore error if empty
-
-	// Generate a state token to link this OAuth request
-	state := fmt.Sprintf("state-%d", time.Now().UnixNano())
-	if req.Usertype != "" {
-		usertypekey := fmt.Sprintf("signup:usertype:%s", state)
-		err := library.SetRedisKeyWithExpiry(a.RedisConnection, usertypekey, req.Usertype, 600)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to store usertype"})
-		}
+// AuthCallback godoc
+// @Summary Complete an identity provider login
+// @Description Exchanges the authorization code, verifies the ID token against the issuer's JWKS, signs in (or creates) the user owning the identity and returns an access and refresh token.
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "Provider name e.g. google"
+// @Param code query string true "Authorization code from the provider"
+// @Param state query string true "State returned by /auth/{provider}/start"
+// @Success 200 {object} map[string]interface{} "token, refresh_token and user"
+// @Failure 400 {object} map[string]string "missing code or state"
+// @Failure 401 {object} map[string]string "invalid identity"
+// @Failure 403 {object} map[string]string "link completed in another browser"
+// @Failure 409 {object} map[string]string "identity or email belongs to another account"
+// @Failure 500 {object} map[string]string "server error"
+// @Router /auth/{provider}/callback [get]
+func (a *App) AuthCallback(c echo.Context) error {
+	provider, ok := a.Providers[c.Param("provider")]
+	if !ok {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "unknown provider"})
 	}
+	return controllers.ProviderCallback(c, a.DB, a.RedisConnection, a.Publisher, provider)
+}
 
-	if req.Phone != "" {
-		// Store phone in Redis temporarily
-		redisKey := fmt.Sprintf("signup:phone:%s", state)
-		err := library.SetRedisKeyWithExpiry(a.RedisConnection, redisKey, req.Phone, 600)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to store phone"})
-		}
-	}
+// ListIdentities godoc
+// @Summary List linked identities
+// @Tags Auth
+// @Produce json
+// @Param api-key header string true "API Key for authentication"
+// @Success 200 {array} models.UserIdentity
+// @Router /auth/identities [get]
+func (a *App) ListIdentities(c echo.Context) error {
+	return controllers.ListIdentities(c, a.DB)
+}
 
-	// Generate Google OAuth URL
-	url := a.GoogleOauthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline)
-	return c.JSON(http.StatusOK, echo.Map{
-		"google_url": url,
-		"state":      state,
-	})
+// UnlinkIdentity godoc
+// @Summary Unlink an identity
+// @Description Removes a provider identity from the calling user, unless it is the only way left to sign in
+// @Tags Auth
+// @Produce json
+// @Param api-key header string true "API Key for authentication"
+// @Param id path int true "Identity ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string "identity not found"
+// @Failure 409 {object} map[string]string "last sign in method"
+// @Router /auth/identities/{id} [delete]
+func (a *App) UnlinkIdentity(c echo.Context) error {
+	return controllers.UnlinkIdentity(c, a.DB)
 }
 
 // UserSignup godoc
 // @Summary Manual user signup
-// @Description Creates a new user directly without Google OAuth (for alternative signup flows).
+// @Description Creates a new customer account with an email and password (alternative to Google OAuth) and emails a verification code.
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -73,108 +100,8 @@ func (a *App) StartGoogleAuth(c echo.Context) error {
 // @Failure 400 {object} map[string]string "Invalid request"
 // @Failure 500 {object} map[string]string "Failed to create user"
 // @Router /auth/signup [post]
-func (a *App) GoogleCallback(c echo.Context) error {
-	code := c.QueryParam("code")
-	state := c.QueryParam("state")
-	if code == "" || state == "" {
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "missing code or state"})
-	}
-
-	// Exchange code for access token
-	token, err := a.GoogleOauthConfig.Exchange(context.Background(), code)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to exchange token"})
-	}
-
-	client := a.GoogleOauthConfig.Client(context.Background(), token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to get user info"})
-	}
-	defer resp.Body.Close()
-
-	var userInfo struct {
-		Email string `json:"email"`
-		Name  string `json:"name"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to parse user info"})
-	}
-
-	// Retrieve phone & usertype from Redis
-	redisKey := fmt.Sprintf("signup:phone:%s", state)
-	usertypeKey := fmt.Sprintf("signup:usertype:%s", state)
-	phone, _ := library.GetRedisKey(a.RedisConnection, redisKey)
-	usertype, _ := library.GetRedisKey(a.RedisConnection, usertypeKey)
-	if usertype == "" {
-		usertype = "customer"
-	}
-
-	// Map usertype → role_id
-	var roleID int
-	err = a.DB.QueryRow("SELECT id FROM roles WHERE name = ?", usertype).Scan(&roleID)
-	if err != nil {
-		// fallback: assign customer role
-		_ = a.DB.QueryRow("SELECT id FROM roles WHERE name = 'customer'").Scan(&roleID)
-	}
-
-	// Check if user exists
-	var userID int64
-	err = a.DB.QueryRow("SELECT id FROM users WHERE email = ?", userInfo.Email).Scan(&userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			// User does not exist → create new one
-			res, insertErr := a.DB.Exec(`
-				INSERT INTO users (email, email_verified, full_name, phone, role_id)
-				VALUES (?, 1, ?, ?, ?)`,
-				userInfo.Email, userInfo.Name, phone, roleID,
-			)
-			if insertErr != nil {
-				return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to create user"})
-			}
-			userID, _ = res.LastInsertId()
-		} else {
-			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to check user"})
-		}
-	}
-	//querry the correct role id
-	a.DB.QueryRow("SELECT role_id,name FROM users WHERE id = ?", userID).Scan(&roleID, &usertype)
-	// Generate JWT
-	claims := &models.JwtCustomClaims{
-		UserID: userID,
-		Email:  userInfo.Email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-		},
-	}
-	var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
-
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, signErr := jwtToken.SignedString(jwtSecret)
-	if signErr != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to sign token"})
-	}
-
-	// Clean up Redis keys
-	a.RedisConnection.Del(redisKey)
-	a.RedisConnection.Del(usertypeKey)
-
-	return c.JSON(http.StatusOK, echo.Map{
-		"message": "authenticated",
-		"token":   tokenString,
-		"user": echo.Map{
-			"id":       userID,
-			"email":    userInfo.Email,
-			"name":     userInfo.Name,
-			"phone":    phone,
-			"role_id":  roleID,
-			"usertype": usertype,
-		},
-	})
-}
-
 func (a *App) UserSignup(c echo.Context) error {
-	user, err := controllers.UserCreate(c, a.DB)
+	user, err := controllers.UserCreate(c, a.DB, a.Publisher)
 	if err != nil {
 		// err might already be an echo.HTTPError
 		if httpErr, ok := err.(*echo.HTTPError); ok {
@@ -183,6 +110,11 @@ func (a *App) UserSignup(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
+	// the account works without it, the user can ask for a new code through /auth/verify/request
+	if err := controllers.SendEmailVerification(c.Request().Context(), a.DB, a.RedisConnection, a.RabbitMQConn, user.ID, user.Email); err != nil {
+		log.Println("failed to send verification code:", err)
+	}
+
 	return c.JSON(http.StatusCreated, map[string]interface{}{
 		"id":    user.ID,
 		"email": user.Email,
@@ -190,3 +122,59 @@ func (a *App) UserSignup(c echo.Context) error {
 		"role":  user.RoleID,
 	})
 }
+
+// UserLogin godoc
+// @Summary Email/password login
+// @Description Authenticates with email (or phone) and password and returns a short-lived access token and a rotating refresh token.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param credentials body controllers.UserLoginRequest true "Login credentials"
+// @Success 200 {object} controllers.UserLoginResponse
+// @Failure 400 {object} map[string]string "invalid request"
+// @Failure 401 {object} map[string]string "invalid credentials"
+// @Router /auth/login [post]
+func (a *App) UserLogin(c echo.Context) error {
+	return controllers.UserLogin(c, a.DB, a.RedisConnection, a.Publisher)
+}
+
+// RefreshToken godoc
+// @Summary Refresh access token
+// @Description Exchanges a refresh token for a new access token and a new refresh token. Reusing a rotated refresh token revokes the whole token family.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body controllers.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} controllers.UserLoginResponse
+// @Failure 400 {object} map[string]string "missing refresh_token"
+// @Failure 401 {object} map[string]string "invalid, revoked or reused refresh token"
+// @Router /auth/refresh [post]
+func (a *App) RefreshToken(c echo.Context) error {
+	return controllers.RefreshTokens(c, a.DB, a.RedisConnection)
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description Revokes the presented access token and the refresh token of the same session. Takes effect immediately in every service.
+// @Tags Auth
+// @Produce json
+// @Param api-key header string true "API Key for authentication"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string "invalid or revoked token"
+// @Router /auth/logout [post]
+func (a *App) Logout(c echo.Context) error {
+	return controllers.Logout(c, a.RedisConnection)
+}
+
+// LogoutAll godoc
+// @Summary Log out all sessions
+// @Description Revokes every access and refresh token issued to the calling user.
+// @Tags Auth
+// @Produce json
+// @Param api-key header string true "API Key for authentication"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string "invalid or revoked token"
+// @Router /auth/logout/all [post]
+func (a *App) LogoutAll(c echo.Context) error {
+	return controllers.LogoutAll(c, a.RedisConnection)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"savannah-store/auth-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ListUsers godoc
+// @Summary      List users
+// @Description  Paginated, newest first. Deleted users are left out unless status is deleted or all.
+// @Tags         Users
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        page query int false "Page, from 1"
+// @Param        per_page query int false "Users per page, at most 100"
+// @Param        role query string false "Role name"
+// @Param        verified query bool false "Email verified"
+// @Param        status query string false "active, disabled, deleted or all"
+// @Param        q query string false "Matches email, phone or full name"
+// @Param        created_from query string false "Created at or after, 2006-01-02 or RFC 3339"
+// @Param        created_to query string false "Created before, a plain date includes the whole day"
+// @Success      200  {object} models.UserList
+// @Failure      400  {object} map[string]string
+// @Router       /auth/admin/users [get]
+func (a *App) ListUsers(c echo.Context) error {
+	return controllers.ListUsers(c, a.DB)
+}
+
+// GetUser godoc
+// @Summary      Get a user
+// @Tags         Users
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id path int true "User ID"
+// @Success      200  {object} models.User
+// @Failure      404  {object} map[string]string
+// @Router       /auth/admin/users/{id} [get]
+func (a *App) GetUser(c echo.Context) error {
+	return controllers.GetUser(c, a.DB)
+}
+
+// UpdateUser godoc
+// @Summary      Update the profile of a user
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id path int true "User ID"
+// @Param        body body models.ProfileUpdate true "Fields to change"
+// @Success      200  {object} models.User
+// @Failure      409  {object} map[string]string "phone is already in use"
+// @Router       /auth/admin/users/{id} [patch]
+func (a *App) UpdateUser(c echo.Context) error {
+	return controllers.UpdateUser(c, a.DB)
+}
+
+// ChangeUserRole godoc
+// @Summary      Change the role of a user
+// @Description  Records the change in role_changes, publishes user.role_changed and logs the user out everywhere
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id path int true "User ID"
+// @Param        body body models.RoleChangeRequest true "New role"
+// @Success      200  {object} map[string]string
+// @Failure      400  {object} map[string]string "unknown role"
+// @Router       /auth/admin/users/{id}/role [put]
+func (a *App) ChangeUserRole(c echo.Context) error {
+	return controllers.ChangeUserRole(c, a.DB, a.RedisConnection, a.Publisher)
+}
+
+// DisableUser godoc
+// @Summary      Disable a user
+// @Description  Blocks every login and ends all sessions of the user
+// @Tags         Users
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id path int true "User ID"
+// @Success      200  {object} map[string]string
+// @Router       /auth/admin/users/{id}/disable [post]
+func (a *App) DisableUser(c echo.Context) error {
+	return controllers.DisableUser(c, a.DB, a.RedisConnection, a.Publisher)
+}
+
+// EnableUser godoc
+// @Summary      Enable a disabled user
+// @Tags         Users
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id path int true "User ID"
+// @Success      200  {object} map[string]string
+// @Router       /auth/admin/users/{id}/enable [post]
+func (a *App) EnableUser(c echo.Context) error {
+	return controllers.EnableUser(c, a.DB, a.RedisConnection, a.Publisher)
+}
+
+// DeleteUser godoc
+// @Summary      Delete a user
+// @Description  Soft delete: the account can never sign in again, the row is kept for order history
+// @Tags         Users
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        id path int true "User ID"
+// @Success      200  {object} map[string]string
+// @Router       /auth/admin/users/{id} [delete]
+func (a *App) DeleteUser(c echo.Context) error {
+	return controllers.DeleteUser(c, a.DB, a.RedisConnection, a.Publisher)
+}
+
+// GetMe godoc
+// @Summary      Get my profile
+// @Tags         Users
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Success      200  {object} models.User
+// @Router       /auth/me [get]
+func (a *App) GetMe(c echo.Context) error {
+	return controllers.GetMe(c, a.DB)
+}
+
+// UpdateMe godoc
+// @Summary      Update my profile
+// @Description  Changes full_name and/or phone, a new phone has to be verified again
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        api-key header string true "API Key for authentication"
+// @Param        body body models.ProfileUpdate true "Fields to change"
+// @Success      200  {object} models.User
+// @Failure      409  {object} map[string]string "phone is already in use"
+// @Router       /auth/me [patch]
+func (a *App) UpdateMe(c echo.Context) error {
+	return controllers.UpdateMe(c, a.DB)
+}
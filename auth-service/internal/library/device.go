@@ -0,0 +1,61 @@
+package library
+
+import "strings"
+
+// DeviceName turns a User-Agent into a short label like "Chrome on Windows" for the session list.
+// It is a best effort guess meant for people to recognise their devices, not for any decision.
+func DeviceName(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	client := ""
+	for _, b := range []struct{ token, name string }{
+		{"edg/", "Edge"},
+		{"opr/", "Opera"},
+		{"firefox/", "Firefox"},
+		{"chrome/", "Chrome"},
+		{"safari/", "Safari"},
+		{"okhttp", "Android app"},
+		{"dart/", "Mobile app"},
+		{"cfnetwork", "iOS app"},
+		{"postman", "Postman"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(ua, b.token) {
+			client = b.name
+			break
+		}
+	}
+
+	platform := ""
+	for _, p := range []struct{ token, name string }{
+		{"android", "Android"},
+		{"iphone", "iPhone"},
+		{"ipad", "iPad"},
+		{"windows", "Windows"},
+		{"mac os x", "macOS"},
+		{"cros", "ChromeOS"},
+		{"linux", "Linux"},
+	} {
+		if strings.Contains(ua, p.token) {
+			platform = p.name
+			break
+		}
+	}
+
+	switch {
+	case client != "" && platform != "":
+		return client + " on " + platform
+	case client != "":
+		return client
+	case platform != "":
+		return platform
+	}
+	// unknown clients usually start with their name, e.g. "ERPConnector/2.1"
+	if name := strings.SplitN(userAgent, "/", 2)[0]; len(name) <= 40 {
+		return name
+	}
+	return "Unknown device"
+}
@@ -0,0 +1,372 @@
+package library
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// how long loaded keys are trusted before the table is read again, so a rotation
+// done by another replica is picked up quickly
+const keyReloadInterval = time.Minute
+
+// SigningKey is one asymmetric key pair used for JWTs
+type SigningKey struct {
+	Kid       string
+	Algorithm string
+	Private   crypto.Signer
+	Public    crypto.PublicKey
+	Created   time.Time
+	RetiredAt *time.Time
+}
+
+// JWK is the public part of a signing key as published on /.well-known/jwks.json
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type keyStore struct {
+	mu     sync.RWMutex
+	db     *sql.DB
+	keys   []*SigningKey // newest first
+	loaded time.Time
+}
+
+var signingKeys = &keyStore{}
+
+// InitSigningKeys loads the signing keys from the database, creating the first key pair when none exists
+func InitSigningKeys(db *sql.DB) error {
+	signingKeys.mu.Lock()
+	signingKeys.db = db
+	signingKeys.mu.Unlock()
+
+	if err := signingKeys.reload(); err != nil {
+		return err
+	}
+
+	// only creates a key when no replica has done so yet
+	_, err := rotateSigningKey(math.MaxInt64)
+	return err
+}
+
+// SigningAlgorithm is the JWT algorithm for new keys, RS256 (default) or EdDSA via JWT_SIGNING_ALG
+func SigningAlgorithm() string {
+	if os.Getenv("JWT_SIGNING_ALG") == jwt.SigningMethodEdDSA.Alg() {
+		return jwt.SigningMethodEdDSA.Alg()
+	}
+	return jwt.SigningMethodRS256.Alg()
+}
+
+// KeyOverlap is how long a retired key stays published and accepted after a rotation,
+// configurable through JWT_KEY_OVERLAP (seconds). It never drops below the access token lifetime.
+func KeyOverlap() time.Duration {
+	overlap := durationFromEnv("JWT_KEY_OVERLAP", AccessTokenTTL()+5*time.Minute)
+	if overlap < AccessTokenTTL() {
+		overlap = AccessTokenTTL()
+	}
+	return overlap
+}
+
+// keyRotationInterval is the age at which the active key is rotated automatically, JWT_KEY_ROTATION (seconds).
+// Zero disables automatic rotation.
+func keyRotationInterval() time.Duration {
+	return durationFromEnv("JWT_KEY_ROTATION", 0)
+}
+
+// ActiveSigningKey returns the key new tokens are signed with
+func ActiveSigningKey() (*SigningKey, error) {
+	keys, err := signingKeys.current()
+	if err != nil {
+		return nil, err
+	}
+
+	key := activeKey(keys)
+	if key == nil {
+		return rotateSigningKey(math.MaxInt64)
+	}
+	if interval := keyRotationInterval(); interval > 0 && time.Since(key.Created) > interval {
+		return rotateSigningKey(interval)
+	}
+	return key, nil
+}
+
+func activeKey(keys []*SigningKey) *SigningKey {
+	for _, k := range keys {
+		if k.RetiredAt == nil {
+			return k
+		}
+	}
+	return nil
+}
+
+// PublishedSigningKeys returns the active key plus the retired keys still inside the overlap window
+func PublishedSigningKeys() ([]*SigningKey, error) {
+	keys, err := signingKeys.current()
+	if err != nil {
+		return nil, err
+	}
+
+	published := []*SigningKey{}
+	for _, k := range keys {
+		if k.RetiredAt == nil || time.Since(*k.RetiredAt) < KeyOverlap() {
+			published = append(published, k)
+		}
+	}
+	return published, nil
+}
+
+// RotateSigningKey generates a new active key and retires the previous one. The retired key keeps
+// verifying tokens for KeyOverlap so tokens already handed out stay valid until they expire.
+func RotateSigningKey() (*SigningKey, error) {
+	return rotateSigningKey(0)
+}
+
+// rotateSigningKey only rotates when the active key is older than maxAge (0 always rotates),
+// which keeps concurrent automatic rotations on several replicas down to one
+func rotateSigningKey(maxAge time.Duration) (*SigningKey, error) {
+	signingKeys.mu.RLock()
+	db := signingKeys.db
+	signingKeys.mu.RUnlock()
+	if db == nil {
+		return nil, fmt.Errorf("signing keys are not initialised")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// lock the active key so two replicas can not rotate at the same time
+	var newest time.Time
+	err = tx.QueryRow(`SELECT created FROM signing_keys WHERE retired_at IS NULL ORDER BY created DESC LIMIT 1 FOR UPDATE`).Scan(&newest)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if err == nil && maxAge > 0 && time.Since(newest) < maxAge {
+		// someone else rotated in the meantime
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		if err := signingKeys.reload(); err != nil {
+			return nil, err
+		}
+		signingKeys.mu.RLock()
+		defer signingKeys.mu.RUnlock()
+		return activeKey(signingKeys.keys), nil
+	}
+
+	key, privatePEM, publicPEM, err := generateSigningKey(SigningAlgorithm())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`UPDATE signing_keys SET retired_at = ? WHERE retired_at IS NULL`, key.Created); err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`INSERT INTO signing_keys (kid, algorithm, private_key, public_key, created) VALUES (?, ?, ?, ?, ?)`,
+		key.Kid, key.Algorithm, privatePEM, publicPEM, key.Created)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if err := signingKeys.reload(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// SigningKeyFunc resolves the verification key of a token from its kid header
+func SigningKeyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	keys, err := PublishedSigningKeys()
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if k.Kid == kid {
+			if t.Method.Alg() != k.Algorithm {
+				return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+			}
+			return k.Public, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// SigningMethods are the algorithms accepted when parsing our own tokens
+func SigningMethods() []string {
+	return []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+}
+
+// JWKS renders the published keys as a JSON Web Key Set
+func JWKS() ([]JWK, error) {
+	keys, err := PublishedSigningKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	set := []JWK{}
+	for _, k := range keys {
+		jwk := JWK{Kid: k.Kid, Use: "sig", Alg: k.Algorithm}
+		switch pub := k.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set = append(set, jwk)
+	}
+	return set, nil
+}
+
+func (s *keyStore) current() ([]*SigningKey, error) {
+	s.mu.RLock()
+	keys, loaded := s.keys, s.loaded
+	s.mu.RUnlock()
+
+	if time.Since(loaded) < keyReloadInterval {
+		return keys, nil
+	}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys, nil
+}
+
+func (s *keyStore) reload() error {
+	s.mu.RLock()
+	db := s.db
+	s.mu.RUnlock()
+	if db == nil {
+		return fmt.Errorf("signing keys are not initialised")
+	}
+
+	rows, err := db.Query(`SELECT kid, algorithm, private_key, created, retired_at FROM signing_keys ORDER BY created DESC`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	keys := []*SigningKey{}
+	for rows.Next() {
+		var (
+			k          SigningKey
+			privatePEM string
+			retiredAt  sql.NullTime
+		)
+		if err := rows.Scan(&k.Kid, &k.Algorithm, &privatePEM, &k.Created, &retiredAt); err != nil {
+			return err
+		}
+		if retiredAt.Valid {
+			k.RetiredAt = &retiredAt.Time
+		}
+
+		k.Private, err = parsePrivateKey(privatePEM)
+		if err != nil {
+			return fmt.Errorf("signing key %s: %v", k.Kid, err)
+		}
+		k.Public = k.Private.Public()
+		keys = append(keys, &k)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.loaded = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func generateSigningKey(alg string) (*SigningKey, string, string, error) {
+	var (
+		private crypto.Signer
+		err     error
+	)
+	if alg == jwt.SigningMethodEdDSA.Alg() {
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	} else {
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, "", "", err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	kid, err := RandomToken(12)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	key := &SigningKey{
+		Kid:       kid,
+		Algorithm: alg,
+		Private:   private,
+		Public:    private.Public(),
+		Created:   time.Now().UTC().Truncate(time.Second),
+	}
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	return key, string(privatePEM), string(publicPEM), nil
+}
+
+func parsePrivateKey(privatePEM string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return signer, nil
+}
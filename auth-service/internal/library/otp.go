@@ -0,0 +1,42 @@
+package library
+
+import (
+	"crypto/rand"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	otpLength             = 6
+	defaultOTPTTL         = 5 * time.Minute
+	defaultOTPMaxAttempts = 5
+)
+
+// GenerateOTP returns a random numeric code
+func GenerateOTP() (string, error) {
+	code := make([]byte, otpLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+	return string(code), nil
+}
+
+// OTPTTL is how long a one time code is valid, configurable through OTP_TTL (seconds)
+func OTPTTL() time.Duration {
+	return durationFromEnv("OTP_TTL", defaultOTPTTL)
+}
+
+// OTPMaxAttempts is how many wrong codes are accepted before the code is burnt, OTP_MAX_ATTEMPTS
+func OTPMaxAttempts() int {
+	attempts, err := strconv.Atoi(os.Getenv("OTP_MAX_ATTEMPTS"))
+	if err != nil || attempts <= 0 {
+		return defaultOTPMaxAttempts
+	}
+	return attempts
+}
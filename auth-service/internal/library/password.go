@@ -0,0 +1,76 @@
+package library
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	defaultPasswordMinLength = 10
+	// bcrypt ignores everything after 72 bytes
+	passwordMaxLength       = 72
+	defaultPasswordResetTTL = 30 * time.Minute
+)
+
+// passwords that pass the character rules but are guessed first
+var commonPasswords = map[string]bool{
+	"password123": true, "password1!": true, "qwerty12345": true, "123456789a": true,
+	"iloveyou123": true, "welcome123!": true, "admin12345": true, "letmein123!": true,
+}
+
+// PasswordResetTTL is how long a reset link works, configurable through PASSWORD_RESET_TTL (seconds)
+func PasswordResetTTL() time.Duration {
+	return durationFromEnv("PASSWORD_RESET_TTL", defaultPasswordResetTTL)
+}
+
+// CheckPasswordStrength enforces the password policy: at least PASSWORD_MIN_LENGTH (default 10) characters,
+// three of lower case, upper case, digits and symbols, not a common password and not containing the email name
+func CheckPasswordStrength(password, email string) error {
+	minLength := defaultPasswordMinLength
+	if n, err := strconv.Atoi(os.Getenv("PASSWORD_MIN_LENGTH")); err == nil && n > 0 {
+		minLength = n
+	}
+
+	if len([]rune(password)) < minLength {
+		return fmt.Errorf("password must be at least %d characters", minLength)
+	}
+	if len(password) > passwordMaxLength {
+		return fmt.Errorf("password must be at most %d bytes", passwordMaxLength)
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			classes++
+		}
+	}
+	if classes < 3 {
+		return fmt.Errorf("password must mix at least three of lower case, upper case, digits and symbols")
+	}
+
+	lowered := strings.ToLower(password)
+	if commonPasswords[lowered] {
+		return fmt.Errorf("password is too common")
+	}
+	if name := strings.ToLower(strings.SplitN(email, "@", 2)[0]); len(name) >= 3 && strings.Contains(lowered, name) {
+		return fmt.Errorf("password must not contain your email")
+	}
+	return nil
+}
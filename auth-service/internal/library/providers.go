@@ -0,0 +1,407 @@
+package library
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"savannah-store/auth-service/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+	"gopkg.in/yaml.v2"
+)
+
+// supported provider types
+const (
+	ProviderGoogle    = "google"
+	ProviderMicrosoft = "microsoft"
+	ProviderGitHub    = "github"
+	ProviderOIDC      = "oidc"
+)
+
+// ProviderConfig describes one identity provider, from env or the AUTH_PROVIDERS_FILE yaml
+type ProviderConfig struct {
+	Name         string   `yaml:"name"`
+	Type         string   `yaml:"type"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Issuer       string   `yaml:"issuer"` // oidc only
+	Tenant       string   `yaml:"tenant"` // microsoft only, defaults to common
+	Scopes       []string `yaml:"scopes"`
+}
+
+// Provider is a configured identity provider. OpenID Connect providers are set up from
+// their discovery document the first time they are used.
+type Provider struct {
+	Name string
+	Type string
+
+	oauth       *oauth2.Config
+	issuer      string
+	issuers     []string // other accepted iss values
+	userInfoURL string
+	keys        *RemoteKeySet
+
+	mu         sync.Mutex
+	discovered bool
+}
+
+// Providers is the registry of configured providers keyed by name
+type Providers map[string]*Provider
+
+var providerHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// LoadProviders builds the registry from the environment and, when AUTH_PROVIDERS_FILE is set,
+// a yaml file (${VAR} references in it are expanded). File entries override env entries of the same name.
+//
+// Env configuration:
+//
+//	GOOGLE_CLIENT_ID, GOOGLE_CLIENT_KEY, GOOGLE_REDIRECT_URL
+//	MICROSOFT_CLIENT_ID, MICROSOFT_CLIENT_SECRET, MICROSOFT_TENANT, MICROSOFT_REDIRECT_URL
+//	GITHUB_CLIENT_ID, GITHUB_CLIENT_SECRET, GITHUB_REDIRECT_URL
+//	OIDC_PROVIDERS=okta,keycloak plus OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL, _SCOPES
+func LoadProviders() (Providers, error) {
+	configs := []ProviderConfig{
+		{Name: ProviderGoogle, Type: ProviderGoogle, ClientID: os.Getenv("GOOGLE_CLIENT_ID"), ClientSecret: os.Getenv("GOOGLE_CLIENT_KEY"), RedirectURL: os.Getenv("GOOGLE_REDIRECT_URL")},
+		{Name: ProviderMicrosoft, Type: ProviderMicrosoft, ClientID: os.Getenv("MICROSOFT_CLIENT_ID"), ClientSecret: os.Getenv("MICROSOFT_CLIENT_SECRET"), RedirectURL: os.Getenv("MICROSOFT_REDIRECT_URL"), Tenant: os.Getenv("MICROSOFT_TENANT")},
+		{Name: ProviderGitHub, Type: ProviderGitHub, ClientID: os.Getenv("GITHUB_CLIENT_ID"), ClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"), RedirectURL: os.Getenv("GITHUB_REDIRECT_URL")},
+	}
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		configs = append(configs, ProviderConfig{
+			Name:         name,
+			Type:         ProviderOIDC,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		})
+	}
+
+	if path := os.Getenv("AUTH_PROVIDERS_FILE"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		var file struct {
+			Providers []ProviderConfig `yaml:"providers"`
+		}
+		if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(raw))), &file); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+		configs = append(configs, file.Providers...)
+	}
+
+	providers := Providers{}
+	for _, cfg := range configs {
+		cfg.Name = strings.ToLower(strings.TrimSpace(cfg.Name))
+		if cfg.ClientID == "" {
+			continue
+		}
+		p, err := newProvider(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %v", cfg.Name, err)
+		}
+		providers[cfg.Name] = p
+	}
+	return providers, nil
+}
+
+// Names returns the configured provider names, sorted
+func (ps Providers) Names() []string {
+	names := make([]string, 0, len(ps))
+	for name := range ps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newProvider(cfg ProviderConfig) (*Provider, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if cfg.Type == "" {
+		cfg.Type = ProviderOIDC
+	}
+
+	// AUTH_PUBLIC_URL is where this service is reachable from browsers, e.g. https://auth.example.com
+	if cfg.RedirectURL == "" && os.Getenv("AUTH_PUBLIC_URL") != "" {
+		cfg.RedirectURL = strings.TrimRight(os.Getenv("AUTH_PUBLIC_URL"), "/") + "/auth/" + cfg.Name + "/callback"
+	}
+
+	p := &Provider{
+		Name: cfg.Name,
+		Type: cfg.Type,
+		oauth: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+		},
+	}
+
+	switch cfg.Type {
+	case ProviderGoogle:
+		p.issuer = "https://accounts.google.com"
+		p.issuers = []string{"accounts.google.com"}
+	case ProviderMicrosoft:
+		tenant := cfg.Tenant
+		if tenant == "" {
+			tenant = "common"
+		}
+		p.issuer = "https://login.microsoftonline.com/" + tenant + "/v2.0"
+	case ProviderGitHub:
+		p.oauth.Endpoint = github.Endpoint
+		p.userInfoURL = "https://api.github.com/user"
+		if len(p.oauth.Scopes) == 0 {
+			p.oauth.Scopes = []string{"read:user", "user:email"}
+		}
+		p.discovered = true
+		return p, nil
+	case ProviderOIDC:
+		if cfg.Issuer == "" {
+			return nil, fmt.Errorf("issuer is required")
+		}
+		p.issuer = strings.TrimRight(cfg.Issuer, "/")
+	default:
+		return nil, fmt.Errorf("unsupported type %q", cfg.Type)
+	}
+
+	if len(p.oauth.Scopes) == 0 {
+		p.oauth.Scopes = []string{"openid", "email", "profile"}
+	}
+	if !containsString(p.oauth.Scopes, "openid") {
+		p.oauth.Scopes = append([]string{"openid"}, p.oauth.Scopes...)
+	}
+	return p, nil
+}
+
+// IsOIDC tells whether the provider returns a verifiable ID token (and so supports the nonce)
+func (p *Provider) IsOIDC() bool {
+	return p.Type != ProviderGitHub
+}
+
+// discover loads the endpoints and key set from the issuer's discovery document, once
+func (p *Provider) discover() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovered {
+		return nil
+	}
+
+	resp, err := providerHTTPClient.Get(p.issuer + "/.well-known/openid-configuration")
+	if err != nil {
+		return fmt.Errorf("failed to fetch discovery document: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch discovery document, status: %s", resp.Status)
+	}
+
+	var doc struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("failed to parse discovery document: %v", err)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return fmt.Errorf("incomplete discovery document")
+	}
+
+	// multi tenant issuers (Microsoft common) publish a {tenantid} template
+	if doc.Issuer != "" {
+		p.issuer = doc.Issuer
+	}
+	p.oauth.Endpoint = oauth2.Endpoint{AuthURL: doc.AuthorizationEndpoint, TokenURL: doc.TokenEndpoint}
+	p.userInfoURL = doc.UserInfoEndpoint
+	p.keys = NewRemoteKeySet(doc.JWKSURI)
+	p.discovered = true
+	return nil
+}
+
+// AuthCodeURL is the provider login URL for the state, with a PKCE challenge and, for OpenID Connect, the nonce
+func (p *Provider) AuthCodeURL(state, verifier, nonce string) (string, error) {
+	if err := p.discover(); err != nil {
+		return "", err
+	}
+
+	opts := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(verifier)}
+	if p.Type == ProviderGoogle {
+		opts = append(opts, oauth2.AccessTypeOffline)
+	}
+	if p.IsOIDC() {
+		opts = append(opts, oauth2.SetAuthURLParam("nonce", nonce))
+	}
+	return p.oauth.AuthCodeURL(state, opts...), nil
+}
+
+// Exchange trades the authorization code for tokens, proving possession of the PKCE verifier
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*oauth2.Token, error) {
+	if err := p.discover(); err != nil {
+		return nil, err
+	}
+	return p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+}
+
+// Identity works out who logged in. OpenID Connect providers are trusted through their signed
+// ID token, which must carry the nonce of the flow; GitHub is asked through its API.
+func (p *Provider) Identity(ctx context.Context, token *oauth2.Token, nonce string) (*models.ExternalIdentity, error) {
+	if p.Type == ProviderGitHub {
+		return p.githubIdentity(ctx, token)
+	}
+
+	idToken, _ := token.Extra("id_token").(string)
+	claims, err := p.verifyIDToken(idToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &models.ExternalIdentity{Provider: p.Name}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	identity.EmailVerified = claimBool(claims["email_verified"])
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("id_token has no subject")
+	}
+
+	// some providers only put the email on the userinfo endpoint
+	if identity.Email == "" && p.userInfoURL != "" {
+		var info struct {
+			Sub           string      `json:"sub"`
+			Email         string      `json:"email"`
+			EmailVerified interface{} `json:"email_verified"`
+			Name          string      `json:"name"`
+		}
+		if err := getJSON(p.oauth.Client(ctx, token), p.userInfoURL, &info); err != nil {
+			return nil, err
+		}
+		if info.Sub != identity.Subject {
+			return nil, fmt.Errorf("userinfo subject mismatch")
+		}
+		identity.Email = info.Email
+		identity.EmailVerified = claimBool(info.EmailVerified)
+		if identity.Name == "" {
+			identity.Name = info.Name
+		}
+	}
+
+	identity.Email = strings.ToLower(identity.Email)
+	return identity, nil
+}
+
+// verifyIDToken checks the signature against the issuer's JWKS and the expiry, audience, issuer and nonce
+func (p *Provider) verifyIDToken(idToken, nonce string) (jwt.MapClaims, error) {
+	if idToken == "" {
+		return nil, fmt.Errorf("no id_token in token response")
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, p.keys.KeyFunc,
+		jwt.WithValidMethods(remoteSigningMethods),
+		jwt.WithAudience(p.oauth.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %v", err)
+	}
+
+	issuer := p.issuer
+	if tid, _ := claims["tid"].(string); tid != "" {
+		issuer = strings.Replace(issuer, "{tenantid}", tid, 1)
+	}
+	iss, _ := claims.GetIssuer()
+	if iss != issuer && !containsString(p.issuers, iss) {
+		return nil, fmt.Errorf("unexpected id_token issuer %s", iss)
+	}
+
+	got, _ := claims["nonce"].(string)
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(got), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("id_token nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+func (p *Provider) githubIdentity(ctx context.Context, token *oauth2.Token) (*models.ExternalIdentity, error) {
+	client := p.oauth.Client(ctx, token)
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := getJSON(client, p.userInfoURL, &user); err != nil {
+		return nil, err
+	}
+	if user.ID == 0 {
+		return nil, fmt.Errorf("github returned no user id")
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(client, p.userInfoURL+"/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &models.ExternalIdentity{Provider: p.Name, Subject: strconv.FormatInt(user.ID, 10), Name: user.Name}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+	// the primary address if it is verified, otherwise any verified one
+	for _, e := range emails {
+		if e.Verified && (e.Primary || identity.Email == "") {
+			identity.Email = strings.ToLower(e.Email)
+			identity.EmailVerified = true
+		}
+	}
+	return identity, nil
+}
+
+func getJSON(client *http.Client, url string, v interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get %s, status: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// claimBool reads boolean claims some providers send as strings
+func claimBool(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == "true"
+	}
+	return false
+}
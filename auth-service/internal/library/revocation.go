@@ -0,0 +1,59 @@
+package library
+
+import (
+	"savannah-store/pkg/authn"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// RevokeToken adds a jti to the denylist until the token would have expired anyway
+func RevokeToken(conn *redis.Client, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	return conn.Set(authn.RevokedTokenKey(jti), 1, ttl).Err()
+}
+
+// RevokeSession revokes the access tokens of a session, they live at most AccessTokenTTL
+func RevokeSession(conn *redis.Client, sid string) error {
+	if sid == "" {
+		return nil
+	}
+	return conn.Set(authn.RevokedSessionKey(sid), 1, AccessTokenTTL()).Err()
+}
+
+// RevokeAllUserTokens revokes every access token issued to the user up to now
+func RevokeAllUserTokens(conn *redis.Client, userID int64) error {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	return conn.Set(authn.RevokedUserKey(userID), now, AccessTokenTTL()).Err()
+}
+
+// RevokeAllClientTokens revokes every access token issued to the machine client up to now
+func RevokeAllClientTokens(conn *redis.Client, clientID string) error {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	return conn.Set(authn.RevokedClientKey(clientID), now, AccessTokenTTL()).Err()
+}
+
+// AwaitRevocationSecond waits, at most a second, until tokens issued now are newer than the user's last user
+// wide revocation. iat only has second precision, so a token signed in the same second would count as revoked.
+func AwaitRevocationSecond(conn *redis.Client, userID int64) {
+	awaitRevocationSecond(conn, authn.RevokedUserKey(userID))
+}
+
+// AwaitClientRevocationSecond is AwaitRevocationSecond for machine clients
+func AwaitClientRevocationSecond(conn *redis.Client, clientID string) {
+	awaitRevocationSecond(conn, authn.RevokedClientKey(clientID))
+}
+
+func awaitRevocationSecond(conn *redis.Client, revokedBeforeKey string) {
+	revokedAt, err := conn.Get(revokedBeforeKey).Int64()
+	if err != nil {
+		return
+	}
+	if wait := time.Until(time.Unix(revokedAt+1, 0)); wait > 0 && wait <= time.Second {
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,42 @@
+package library
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+var serviceClient = &http.Client{Timeout: 10 * time.Second}
+
+// CallService calls an internal endpoint of another service with the shared INTERNAL_API_TOKEN. urlEnv names
+// the env var holding the base url of the service, e.g. ORDER_SERVICE_URL. A 2xx answer is decoded into out
+// unless out is nil.
+func CallService(ctx context.Context, method, urlEnv, path string, out interface{}) error {
+	base := os.Getenv(urlEnv)
+	if base == "" {
+		return fmt.Errorf("%s is not set", urlEnv)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, base+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Internal-Token", os.Getenv("INTERNAL_API_TOKEN"))
+
+	resp, err := serviceClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s failed, status: %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,131 @@
+package library
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+	defaultInviteTokenTTL  = 7 * 24 * time.Hour
+	// rotated client secrets keep working this long so integrations can be redeployed
+	defaultClientSecretGracePeriod = 24 * time.Hour
+	defaultImpersonationTTL        = 15 * time.Minute
+)
+
+// JWT typ headers, so a token minted for one purpose is never accepted for another
+const (
+	AccessTokenType = "JWT"
+	InviteTokenType = "invite+jwt"
+)
+
+// AccessTokenTTL is the lifetime of access JWTs, configurable through ACCESS_TOKEN_TTL (seconds)
+func AccessTokenTTL() time.Duration {
+	return durationFromEnv("ACCESS_TOKEN_TTL", defaultAccessTokenTTL)
+}
+
+// RefreshTokenTTL is the lifetime of refresh tokens, configurable through REFRESH_TOKEN_TTL (seconds)
+func RefreshTokenTTL() time.Duration {
+	return durationFromEnv("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+}
+
+// InviteTokenTTL is how long a role invitation can be accepted, configurable through INVITE_TOKEN_TTL (seconds)
+func InviteTokenTTL() time.Duration {
+	return durationFromEnv("INVITE_TOKEN_TTL", defaultInviteTokenTTL)
+}
+
+// ClientSecretGracePeriod is how long a rotated client secret stays valid, CLIENT_SECRET_GRACE_PERIOD (seconds)
+func ClientSecretGracePeriod() time.Duration {
+	return durationFromEnv("CLIENT_SECRET_GRACE_PERIOD", defaultClientSecretGracePeriod)
+}
+
+// ImpersonationTTL is the lifetime of impersonation tokens, IMPERSONATION_TTL (seconds). They are never
+// refreshed and live at most AccessTokenTTL, so the revocation keys always outlive them.
+func ImpersonationTTL() time.Duration {
+	ttl := durationFromEnv("IMPERSONATION_TTL", defaultImpersonationTTL)
+	if max := AccessTokenTTL(); ttl > max {
+		return max
+	}
+	return ttl
+}
+
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || seconds <= 0 {
+		return fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// SignToken signs access token claims with the active signing key and sets its kid header
+func SignToken(claims jwt.Claims) (string, error) {
+	return SignTypedToken(claims, AccessTokenType)
+}
+
+// SignTypedToken signs claims with the active signing key and marks the token with the given typ header
+func SignTypedToken(claims jwt.Claims, typ string) (string, error) {
+	key, err := ActiveSigningKey()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.Kid
+	token.Header["typ"] = typ
+	return token.SignedString(key.Private)
+}
+
+// ParseToken verifies an access token signed by this service and fills claims
+func ParseToken(tokenString string, claims jwt.Claims) error {
+	return ParseTypedToken(tokenString, claims, AccessTokenType)
+}
+
+// ParseTypedToken verifies a token signed by this service and carrying the given typ header
+func ParseTypedToken(tokenString string, claims jwt.Claims, typ string) error {
+	keyFunc := func(t *jwt.Token) (interface{}, error) {
+		if tokenType(t) != typ {
+			return nil, fmt.Errorf("unexpected token type")
+		}
+		return SigningKeyFunc(t)
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc, jwt.WithValidMethods(SigningMethods()))
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return fmt.Errorf("invalid token")
+	}
+	return nil
+}
+
+// tokenType reads the typ header, tokens without one are access tokens
+func tokenType(t *jwt.Token) string {
+	if typ, _ := t.Header["typ"].(string); typ != "" {
+		return typ
+	}
+	return AccessTokenType
+}
+
+// RandomToken returns a URL safe random string built from n random bytes
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex encoded sha256 of an opaque token, used so raw tokens never hit storage
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"savannah-store/auth-service/internal/controllers"
+	"savannah-store/auth-service/internal/library"
+	"savannah-store/auth-service/internal/models"
+	"savannah-store/pkg/authn"
+	"sync"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+)
+
+// Authenticated validates the api-key or Authorization: Bearer token (JWT), rejects revoked tokens and stores the claims in the context
+func Authenticated(rdb *redis.Client) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, httpErr := authenticate(c, rdb)
+			if httpErr != nil {
+				return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+			}
+
+			authn.SetContext(c, claims)
+			return next(c)
+		}
+	}
+}
+
+// OptionalAuthenticated is Authenticated for routes that also serve anonymous callers,
+// it only rejects a request that carries an invalid token
+func OptionalAuthenticated(rdb *redis.Client) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if authn.TokenFromRequest(c.Request(), authn.DefaultHeaders) == "" {
+				return next(c)
+			}
+			return Authenticated(rdb)(next)(c)
+		}
+	}
+}
+
+// PermissionMiddleware is Authenticated plus a check that the user's role is granted action on module
+func PermissionMiddleware(db *sql.DB, rdb *redis.Client, module, action string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, httpErr := authenticate(c, rdb)
+			if httpErr != nil {
+				return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+			}
+
+			allowed, err := controllers.HasPermission(c.Request().Context(), db, claims.UserID, module, action)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to fetch permissions"})
+			}
+			if !allowed {
+				return c.JSON(http.StatusForbidden, echo.Map{"error": fmt.Sprintf("missing permission %s:%s", module, action)})
+			}
+
+			authn.SetContext(c, claims)
+			return next(c)
+		}
+	}
+}
+
+// authenticator verifies tokens with this service's own signing keys, it is created on first use
+var (
+	authenticator     *authn.Authenticator
+	authenticatorOnce sync.Once
+)
+
+func authenticate(c echo.Context, rdb *redis.Client) (*models.JwtCustomClaims, *echo.HTTPError) {
+	authenticatorOnce.Do(func() {
+		authenticator = authn.New(authn.Config{Redis: rdb, Service: "auth-service", Parse: library.ParseToken})
+	})
+
+	claims, httpErr := authenticator.Authenticate(c)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	// machine client tokens are for the catalog API, auth-service endpoints act on a user
+	if claims.ClientID != "" {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "client tokens are not accepted here")
+	}
+
+	// an impersonating admin acts as the customer in the shop, never on the customer's account itself
+	if claims.Act != nil {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "impersonation tokens are not accepted here")
+	}
+
+	return claims, nil
+}
@@ -1,13 +1,9 @@
 package models
 
-import (
+import "savannah-store/pkg/authn"
 
-	"github.com/golang-jwt/jwt/v5"
-)
-
-type JwtCustomClaims struct {
-		UserID int64  `json:"user_id"`
-		Email  string `json:"email"`
-		jwt.RegisteredClaims
-	}
+// JwtCustomClaims are the access token claims, shared with the other services through authn
+type JwtCustomClaims = authn.Claims
 
+// Actor is the admin acting as the user of an impersonation token
+type Actor = authn.Actor
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// OAuthClient is a machine client that gets access tokens with the client credentials grant
+type OAuthClient struct {
+	ClientID   string         `json:"client_id"`
+	Name       string         `json:"name"`
+	Scopes     []string       `json:"scopes"`
+	CreatedBy  int64          `json:"created_by,omitempty"`
+	Created    time.Time      `json:"created"`
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty"`
+	DisabledAt *time.Time     `json:"disabled_at,omitempty"`
+	Secrets    []ClientSecret `json:"secrets"`
+	// only returned when the client is created or its secret rotated
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// ClientSecret describes a secret of a client, never the secret itself
+type ClientSecret struct {
+	ID         int64      `json:"id"`
+	Hint       string     `json:"hint"`
+	Created    time.Time  `json:"created"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// ClientRequest creates a client or replaces its name and scopes
+type ClientRequest struct {
+	Name   string   `json:"name" example:"ERP stock sync"`
+	Scopes []string `json:"scopes" example:"product:read,product:update"`
+}
+
+// ClientTokenRequest is the client credentials grant (RFC 6749 section 4.4), the credentials may come
+// in the form or as HTTP Basic auth
+type ClientTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	Scope        string `form:"scope"`
+}
+
+// ClientTokenResponse is the access token response of the client credentials grant
+type ClientTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
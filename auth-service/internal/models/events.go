@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// UserCreatedEvent is published as user.created when an account is created, by signup or by a first provider login
+type UserCreatedEvent struct {
+	UserID        int64  `json:"user_id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Phone         string `json:"phone,omitempty"`
+	FullName      string `json:"full_name,omitempty"`
+	Role          string `json:"role"`
+	// password, or the name of the identity provider
+	Method    string    `json:"method"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserVerifiedEvent is published as user.verified the first time the email or phone of a user is proven
+type UserVerifiedEvent struct {
+	UserID int64  `json:"user_id"`
+	Email  string `json:"email"`
+	Phone  string `json:"phone,omitempty"`
+	// email or phone
+	Attribute  string    `json:"attribute"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
+
+// UserStatusEvent is published as user.disabled, user.enabled and user.deleted
+type UserStatusEvent struct {
+	UserID    int64     `json:"user_id"`
+	Email     string    `json:"email"`
+	ChangedBy int64     `json:"changed_by,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// UserMFAResetEvent is published as user.mfa_reset when an admin removes the 2FA of a user
+type UserMFAResetEvent struct {
+	UserID  int64     `json:"user_id"`
+	ResetBy int64     `json:"reset_by"`
+	ResetAt time.Time `json:"reset_at"`
+}
+
+// UserErasedEvent is published as user.erased once the personal data of a user is erased in every service,
+// consumers drop whatever they copied about the user
+type UserErasedEvent struct {
+	UserID      int64     `json:"user_id"`
+	JobID       int64     `json:"job_id"`
+	RequestedBy int64     `json:"requested_by"`
+	ErasedAt    time.Time `json:"erased_at"`
+}
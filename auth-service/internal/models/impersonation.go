@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// ImpersonationRequest starts an impersonation, the reason ends up in the audit trail
+type ImpersonationRequest struct {
+	Reason string `json:"reason" example:"customer cannot check out, ticket #4411"`
+}
+
+// Impersonation is an admin acting as a user for a limited time
+type Impersonation struct {
+	ID        string     `json:"id"`
+	AdminID   int64      `json:"admin_id"`
+	UserID    int64      `json:"user_id"`
+	Reason    string     `json:"reason"`
+	StartedAt time.Time  `json:"started_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	// only set when the impersonation starts
+	Token string `json:"token,omitempty"`
+}
+
+// ImpersonationAuditEntry is one request served with an impersonation token
+type ImpersonationAuditEntry struct {
+	ImpersonationID string    `json:"impersonation_id"`
+	AdminID         int64     `json:"admin_id"`
+	UserID          int64     `json:"user_id"`
+	Service         string    `json:"service"`
+	Method          string    `json:"method"`
+	Path            string    `json:"path"`
+	Status          int       `json:"status"`
+	IP              string    `json:"ip,omitempty"`
+	Created         time.Time `json:"created"`
+}
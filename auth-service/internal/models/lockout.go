@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// LoginFailedEvent is published as user.login_failed for every failed password login
+type LoginFailedEvent struct {
+	UserID     int64     `json:"user_id,omitempty"`
+	Identifier string    `json:"identifier"`
+	IP         string    `json:"ip"`
+	Failures   int64     `json:"failures"`
+	IPFailures int64     `json:"ip_failures"`
+	FailedAt   time.Time `json:"failed_at"`
+}
+
+// UserLockedEvent is published as user.locked when an account or a client IP is locked out,
+// UserID and Identifier are empty for an IP lock
+type UserLockedEvent struct {
+	UserID     int64     `json:"user_id,omitempty"`
+	Identifier string    `json:"identifier,omitempty"`
+	IP         string    `json:"ip"`
+	Failures   int64     `json:"failures"`
+	LockedAt   time.Time `json:"locked_at"`
+	Until      time.Time `json:"until"`
+}
+
+// UserUnlockedEvent is published as user.unlocked when an admin lifts the lock of a user, IP is set when the
+// lock of a client IP was lifted along with it
+type UserUnlockedEvent struct {
+	UserID     int64     `json:"user_id"`
+	IP         string    `json:"ip,omitempty"`
+	UnlockedBy int64     `json:"unlocked_by"`
+	UnlockedAt time.Time `json:"unlocked_at"`
+}
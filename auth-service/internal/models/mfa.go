@@ -0,0 +1,26 @@
+package models
+
+// the payload for enrolling in 2FA, the challenge is only needed when 2FA is mandatory and the
+// user is enrolling during login (without an api-key)
+type MFAEnrollRequest struct {
+	Challenge string `json:"challenge,omitempty"`
+}
+
+// the payload carrying a TOTP code (or a recovery code where allowed)
+type MFACodeRequest struct {
+	Challenge string `json:"challenge,omitempty"`
+	Code      string `json:"code" example:"123456"`
+}
+
+// MFAEnrollment is returned when enrolling, the secret is shown once for apps that can not scan the QR code
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURL string `json:"otpauth_url"`
+}
+
+// MFAChallenge is kept in redis between the first and the second login step
+type MFAChallenge struct {
+	UserID int64 `json:"user_id"`
+	// Enroll marks a login of a user who must set up 2FA before getting tokens
+	Enroll bool `json:"enroll,omitempty"`
+}
@@ -0,0 +1,32 @@
+package models
+
+// Notification is the message notification-service consumes from notification_queue
+type Notification struct {
+	Type    string `json:"type"`              // "sms" or "email"
+	To      string `json:"to,omitempty"`      // phone or email
+	Subject string `json:"subject,omitempty"` // for emails
+	Message string `json:"message"`
+}
+
+// the payload for asking a one time login code, by email or by phone
+type OTPRequest struct {
+	Email string `json:"email,omitempty" example:"test@example.com"`
+	Phone string `json:"phone,omitempty" example:"0712345678"`
+}
+
+// the payload for logging in with a one time code
+type OTPLoginRequest struct {
+	Email string `json:"email,omitempty" example:"test@example.com"`
+	Phone string `json:"phone,omitempty" example:"0712345678"`
+	Code  string `json:"code" example:"123456"`
+}
+
+// the payload for asking a verification code for the email ("email") or phone ("sms") of the account
+type VerificationRequest struct {
+	Channel string `json:"channel" example:"email"`
+}
+
+// the payload for confirming a verification code
+type VerificationConfirmRequest struct {
+	Code string `json:"code" example:"123456"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// the payload for asking a password reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email" example:"test@example.com"`
+}
+
+// the payload for setting a new password with a reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password" example:"N3w-Strong-Pass"`
+}
+
+// the payload for changing the password of the logged in user
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" example:"StrongPass123"`
+	NewPassword     string `json:"new_password" example:"N3w-Strong-Pass"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RoleChange is one entry of the role history of a user
+type RoleChange struct {
+	OldRole   string    `json:"old_role,omitempty"`
+	NewRole   string    `json:"new_role"`
+	ChangedBy *int64    `json:"changed_by,omitempty"`
+	Source    string    `json:"source"`
+	Created   time.Time `json:"created"`
+}
+
+// AuthPersonalData is everything auth-service holds about a user
+type AuthPersonalData struct {
+	User           User            `json:"user"`
+	Identities     []UserIdentity  `json:"identities"`
+	MFAEnabled     bool            `json:"mfa_enabled"`
+	Sessions       []Session       `json:"sessions"`
+	RoleChanges    []RoleChange    `json:"role_changes"`
+	RoleRequests   []RoleElevation `json:"role_requests"`
+	Impersonations []Impersonation `json:"impersonations"`
+}
+
+// PersonalDataExport is everything held about a user across the services. Services holds the answer of
+// each other service as it was given, keyed by service name.
+type PersonalDataExport struct {
+	UserID      int64                      `json:"user_id"`
+	GeneratedAt time.Time                  `json:"generated_at"`
+	Auth        *AuthPersonalData          `json:"auth_service"`
+	Services    map[string]json.RawMessage `json:"services" swaggertype:"object"`
+}
+
+// ErasureJob is a request to erase the personal data of a user in every service
+type ErasureJob struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	RequestedBy int64      `json:"requested_by"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	LastError   string     `json:"last_error,omitempty"`
+	Created     time.Time  `json:"created"`
+	Updated     time.Time  `json:"updated"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
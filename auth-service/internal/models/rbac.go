@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Actions every module supports, matching the permissions.action enum
+var PermissionActions = []string{"create", "read", "update", "delete"}
+
+type Role struct {
+	ID          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Permissions []string `json:"permissions"`
+}
+
+type Permission struct {
+	ID          int64  `json:"id"`
+	Module      string `json:"module"`
+	Action      string `json:"action"`
+	Description string `json:"description,omitempty"`
+}
+
+// the payload for creating a role
+type RoleRequest struct {
+	Name        string `json:"name" example:"support"`
+	Description string `json:"description" example:"Customer support agents"`
+}
+
+// the payload for creating a module, its CRUD permissions are seeded automatically
+type ModuleRequest struct {
+	Name string `json:"name" example:"inventory"`
+}
+
+// the payload for inviting someone into a role
+type InviteRequest struct {
+	Email string `json:"email" example:"jane@example.com"`
+	Role  string `json:"role" example:"admin"`
+}
+
+// RoleInvite is an issued invitation, Token and URL are only returned when it is created
+type RoleInvite struct {
+	ID         string     `json:"id"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	InvitedBy  int64      `json:"invited_by"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedBy *int64     `json:"accepted_by,omitempty"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	Created    time.Time  `json:"created"`
+	Token      string     `json:"token,omitempty"`
+	URL        string     `json:"url,omitempty"`
+}
+
+// InviteClaims are carried by signed invite tokens, the jti is the role_invites id
+type InviteClaims struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// the payload for accepting an invitation
+type AcceptInviteRequest struct {
+	Invite string `json:"invite"`
+}
+
+// the payload a user sends to ask for a role
+type ElevationRequest struct {
+	Role   string `json:"role" example:"admin"`
+	Reason string `json:"reason" example:"I manage the product catalogue"`
+}
+
+// RoleElevation is a request for a role waiting for (or given) an admin decision
+type RoleElevation struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Email     string     `json:"email"`
+	Role      string     `json:"role"`
+	Reason    string     `json:"reason,omitempty"`
+	Status    string     `json:"status"`
+	DecidedBy *int64     `json:"decided_by,omitempty"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	Created   time.Time  `json:"created"`
+}
+
+// RoleChangedEvent is published as user.role_changed on the user.events exchange
+type RoleChangedEvent struct {
+	UserID    int64     `json:"user_id"`
+	Email     string    `json:"email"`
+	OldRole   string    `json:"old_role"`
+	NewRole   string    `json:"new_role"`
+	ChangedBy int64     `json:"changed_by,omitempty"`
+	Source    string    `json:"source"`
+	ChangedAt time.Time `json:"changed_at"`
+}
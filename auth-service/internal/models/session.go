@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Session is one login of a user, i.e. one refresh token family. It is kept in redis next to the
+// family and lives as long as the refresh tokens of the family.
+type Session struct {
+	ID        string    `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Device    string    `json:"device"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Created   time.Time `json:"created"`
+	// updated whenever the session refreshes its tokens
+	LastSeen time.Time `json:"last_seen"`
+	// true for the session of the access token that asked
+	Current bool `json:"current,omitempty"`
+}
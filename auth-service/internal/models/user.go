@@ -3,28 +3,100 @@ package models
 import "time"
 
 type User struct {
-	ID            int64     `db:"id" json:"id"`
-	Email         string    `db:"email" json:"email"`
-	EmailVerified bool      `db:"email_verified" json:"email_verified"`
-	Phone         string    `db:"phone" json:"phone,omitempty"`
-	FullName      string    `db:"full_name" json:"full_name"`
-	PasswordHash  string    `db:"password_hash" json:"-"`
-	OTP           string    `db:"otp" json:"-"`
-	Role          string    `db:"role" json:"role"`
-	OIDCProvider  string    `db:"oidc_provider" json:"oidc_provider,omitempty"`
-	OIDCSub       string    `db:"oidc_sub" json:"oidc_sub,omitempty"`
-	Created       time.Time `db:"created" json:"created"`
-	Updated       time.Time `db:"updated" json:"updated"`
-}
-
-//the payload for starting Google OAuth
+	ID            int64      `db:"id" json:"id"`
+	Email         string     `db:"email" json:"email"`
+	EmailVerified bool       `db:"email_verified" json:"email_verified"`
+	Phone         string     `db:"phone" json:"phone,omitempty"`
+	PhoneVerified bool       `db:"phone_verified" json:"phone_verified"`
+	FullName      string     `db:"full_name" json:"full_name"`
+	PasswordHash  string     `db:"password_hash" json:"-"`
+	RoleID        int64      `db:"role_id" json:"role_id"`
+	Role          string     `db:"role" json:"role"`
+	DisabledAt    *time.Time `db:"disabled_at" json:"disabled_at,omitempty"`
+	DeletedAt     *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+	Created       time.Time  `db:"created" json:"created"`
+	Updated       time.Time  `db:"updated" json:"updated"`
+}
+
+// UserFilter narrows the admin user listing, zero values do not filter
+type UserFilter struct {
+	Role     string
+	Verified *bool
+	// active, disabled, deleted or all; empty lists everything but deleted users
+	Status      string
+	Query       string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Page        int
+	PerPage     int
+}
+
+// UserList is one page of the admin user listing
+type UserList struct {
+	Users   []User `json:"users"`
+	Page    int    `json:"page"`
+	PerPage int    `json:"per_page"`
+	Total   int    `json:"total"`
+}
+
+// ProfileUpdate changes the full name and/or phone, nil fields are left alone
+type ProfileUpdate struct {
+	FullName *string `json:"full_name,omitempty" example:"Jane Wanjiku"`
+	Phone    *string `json:"phone,omitempty" example:"0712345678"`
+}
+
+// RoleChangeRequest is an admin moving a user to another role
+type RoleChangeRequest struct {
+	Role string `json:"role" example:"admin"`
+}
+
+//the payload for starting Google OAuth, new accounts are always customers unless an invite is passed
 type AuthRequest struct {
-    Phone    string `json:"phone" example:"0712345678"`
-    Usertype string `json:"usertype" example:"customer"`
+    Phone  string `json:"phone" example:"0712345678"`
+    Invite string `json:"invite,omitempty" example:"eyJhbGciOi..."`
 }
 type UserSignupRequest struct {
     Email    string `json:"email" example:"test@example.com"`
     Phone    string `json:"phone" example:"0712345678"`
     Password string `json:"password" example:"StrongPass123"`
-    RoleID   int64  `json:"role_id" example:"2"`
+}
+
+// UserProfile is what other services get back from the internal user lookup API
+type UserProfile struct {
+	ID            int64  `json:"id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Phone         string `json:"phone,omitempty"`
+	FullName      string `json:"full_name,omitempty"`
+	Role          string `json:"role"`
+}
+
+// OAuthState is kept in redis between /auth/{provider}/start and the callback, keyed by the state value
+type OAuthState struct {
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce,omitempty"`
+	Phone        string `json:"phone,omitempty"`
+	Invite       string `json:"invite,omitempty"`
+	// set when a logged in user started the flow to link another identity
+	LinkUserID int64 `json:"link_user_id,omitempty"`
+}
+
+// ExternalIdentity is the user as described by an identity provider after a successful login
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// UserIdentity is a provider account linked to a user, unique on (provider, subject)
+type UserIdentity struct {
+	ID        int64      `json:"id"`
+	Provider  string     `json:"provider"`
+	Subject   string     `json:"subject"`
+	Email     string     `json:"email,omitempty"`
+	Created   time.Time  `json:"created"`
+	LastLogin *time.Time `json:"last_login,omitempty"`
 }
@@ -4,51 +4,207 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"savannah-store/auth-service/internal/models"
 )
 
+var ErrUserNotFound = errors.New("user not found")
+
+const userColumns = `
+	SELECT u.id, u.email, COALESCE(u.email_verified, 0), COALESCE(u.phone, ''), COALESCE(u.phone_verified, 0),
+	       COALESCE(u.full_name, ''), COALESCE(u.password_hash, ''), COALESCE(u.role_id, 0), COALESCE(r.name, ''),
+	       u.disabled_at, u.deleted_at, u.created, u.updated
+	FROM users u
+	LEFT JOIN roles r ON r.id = u.role_id`
+
 type UserRepository struct {
 	db *sql.DB
 }
 
 func NewUserRepository(db *sql.DB) *UserRepository { return &UserRepository{db: db} }
 
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	u := &models.User{}
+	var disabledAt, deletedAt sql.NullTime
+	err := row.Scan(&u.ID, &u.Email, &u.EmailVerified, &u.Phone, &u.PhoneVerified, &u.FullName, &u.PasswordHash,
+		&u.RoleID, &u.Role, &disabledAt, &deletedAt, &u.Created, &u.Updated)
+	if err != nil {
+		return nil, err
+	}
+	if disabledAt.Valid {
+		u.DisabledAt = &disabledAt.Time
+	}
+	if deletedAt.Valid {
+		u.DeletedAt = &deletedAt.Time
+	}
+	return u, nil
+}
+
 func (r *UserRepository) SaveUser(ctx context.Context, u *models.User) error {
-	q := `INSERT INTO user (email, email_verified, phone, full_name, password_hash, otp, role, oidc_provider, oidc_sub, created, updated)
-	      VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	q := `INSERT INTO users (email, email_verified, phone, full_name, password_hash, role_id, created, updated)
+	      VALUES (?, ?, NULLIF(?, ''), ?, NULLIF(?, ''), NULLIF(?, 0), ?, ?)`
+	now := time.Now()
 	res, err := r.db.ExecContext(ctx, q,
-		u.Email, u.EmailVerified, u.Phone, u.FullName, u.PasswordHash, u.OTP, u.Role, u.OIDCProvider, u.OIDCSub, time.Now(), time.Now(),
+		u.Email, u.EmailVerified, u.Phone, u.FullName, u.PasswordHash, u.RoleID, now, now,
 	)
 	if err != nil {
 		return err
 	}
 	id, _ := res.LastInsertId()
 	u.ID = id
+	u.Created, u.Updated = now, now
 	return nil
 }
 
 func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
-	q := `SELECT id,email,email_verified,phone,full_name,password_hash,otp,role,oidc_provider,oidc_sub,created,updated FROM user WHERE email=? LIMIT 1`
-	row := r.db.QueryRowContext(ctx, q, email)
-	u := &models.User{}
-	err := row.Scan(&u.ID, &u.Email, &u.EmailVerified, &u.Phone, &u.FullName, &u.PasswordHash, &u.OTP, &u.Role, &u.OIDCProvider, &u.OIDCSub, &u.Created, &u.Updated)
+	u, err := scanUser(r.db.QueryRowContext(ctx, userColumns+` WHERE u.email = ? LIMIT 1`, email))
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	return u, err
+}
+
+func (r *UserRepository) FindByID(ctx context.Context, id int64) (*models.User, error) {
+	u, err := scanUser(r.db.QueryRowContext(ctx, userColumns+` WHERE u.id = ? LIMIT 1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	return u, err
+}
+
+// ListUsers returns the page of users matching the filter, newest first, and how many match in total
+func (r *UserRepository) ListUsers(ctx context.Context, f models.UserFilter) ([]models.User, int, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	switch f.Status {
+	case "active":
+		where = append(where, "u.deleted_at IS NULL AND u.disabled_at IS NULL")
+	case "disabled":
+		where = append(where, "u.deleted_at IS NULL AND u.disabled_at IS NOT NULL")
+	case "deleted":
+		where = append(where, "u.deleted_at IS NOT NULL")
+	case "all":
+	default:
+		where = append(where, "u.deleted_at IS NULL")
+	}
+	if f.Role != "" {
+		where = append(where, "r.name = ?")
+		args = append(args, f.Role)
+	}
+	if f.Verified != nil {
+		where = append(where, "COALESCE(u.email_verified, 0) = ?")
+		args = append(args, *f.Verified)
+	}
+	if f.Query != "" {
+		like := "%" + f.Query + "%"
+		where = append(where, "(u.email LIKE ? OR u.phone LIKE ? OR u.full_name LIKE ?)")
+		args = append(args, like, like, like)
+	}
+	if f.CreatedFrom != nil {
+		where = append(where, "u.created >= ?")
+		args = append(args, *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		where = append(where, "u.created < ?")
+		args = append(args, *f.CreatedTo)
+	}
+	cond := ""
+	if len(where) > 0 {
+		cond = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users u LEFT JOIN roles r ON r.id = u.role_id`+cond, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, userColumns+cond+` ORDER BY u.created DESC, u.id DESC LIMIT ? OFFSET ?`,
+		append(args, f.PerPage, (f.Page-1)*f.PerPage)...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("user not found")
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
 		}
-		return nil, err
+		users = append(users, *u)
 	}
-	return u, nil
+	return users, total, rows.Err()
 }
 
-func (r *UserRepository) FindByID(ctx context.Context, id int64) (*models.User, error) {
-	q := `SELECT id,email,email_verified,phone,full_name,password_hash,otp,role,oidc_provider,oidc_sub,created,updated FROM user WHERE id=? LIMIT 1`
-	row := r.db.QueryRowContext(ctx, q, id)
-	u := &models.User{}
-	if err := row.Scan(&u.ID, &u.Email, &u.EmailVerified, &u.Phone, &u.FullName, &u.PasswordHash, &u.OTP, &u.Role, &u.OIDCProvider, &u.OIDCSub, &u.Created, &u.Updated); err != nil {
-		return nil, err
+// UpdateProfile sets the full name and phone that are not nil, a new phone has to be verified again
+func (r *UserRepository) UpdateProfile(ctx context.Context, id int64, p models.ProfileUpdate) error {
+	var (
+		set  []string
+		args []interface{}
+	)
+	if p.FullName != nil {
+		set = append(set, "full_name = ?")
+		args = append(args, *p.FullName)
 	}
-	return u, nil
+	if p.Phone != nil {
+		set = append(set, "phone_verified = IF(COALESCE(phone, '') = ?, phone_verified, 0)", "phone = NULLIF(?, '')")
+		args = append(args, *p.Phone, *p.Phone)
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET `+strings.Join(set, ", ")+` WHERE id = ? AND deleted_at IS NULL`, append(args, id)...)
+	return err
+}
+
+// PhoneTaken tells whether another live account already uses the phone
+func (r *UserRepository) PhoneTaken(ctx context.Context, phone string, exceptID int64) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE phone = ? AND id <> ? AND deleted_at IS NULL`, phone, exceptID).Scan(&n)
+	return n > 0, err
+}
+
+// SetDisabled disables or enables an account that is not deleted
+func (r *UserRepository) SetDisabled(ctx context.Context, id int64, disabled bool) error {
+	q := `UPDATE users SET disabled_at = NULL WHERE id = ? AND deleted_at IS NULL`
+	if disabled {
+		q = `UPDATE users SET disabled_at = COALESCE(disabled_at, NOW()) WHERE id = ? AND deleted_at IS NULL`
+	}
+	res, err := r.db.ExecContext(ctx, q, id)
+	if err != nil {
+		return err
+	}
+	return requireRow(ctx, r.db, res, id)
+}
+
+// SoftDelete marks the account deleted, the row stays for the orders and audit trail that point at it
+func (r *UserRepository) SoftDelete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET deleted_at = NOW(), disabled_at = COALESCE(disabled_at, NOW()) WHERE id = ? AND deleted_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	return requireRow(ctx, r.db, res, id)
+}
+
+// requireRow turns an update that matched nothing into ErrUserNotFound. MySQL reports unchanged rows as
+// unaffected, so a live user that was already in the requested state is looked up before failing.
+func requireRow(ctx context.Context, db *sql.DB, res sql.Result, id int64) error {
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	var exists int
+	err := db.QueryRowContext(ctx, `SELECT 1 FROM users WHERE id = ? AND deleted_at IS NULL`, id).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	return err
 }
@@ -7,7 +7,7 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/joho/godotenv"
 
-	"savannah-store/auth-service/internal/repository"
+	"savannah-store/pkg/dbx"
 
 	_ "savannah-store/auth-service/docs"
 
@@ -30,7 +30,7 @@ func main() {
 	}
 
 	// setup database
-	dbInstance := repository.DbInstance(os.Getenv("AUTH_DB_NAME"))
+	dbInstance := dbx.Open(os.Getenv("AUTH_DB_NAME"))
 
 	driver, err := mysql.WithInstance(dbInstance, &mysql.Config{})
 	if err != nil {
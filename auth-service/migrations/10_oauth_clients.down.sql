@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS oauth_client_secrets;
+DROP TABLE IF EXISTS oauth_clients;
+DELETE FROM modules WHERE name = 'client';
@@ -0,0 +1,45 @@
+-- machine clients (ERP, back-office jobs) that get access tokens with the OAuth2 client credentials grant
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    -- space separated module:action permissions the client may ask for
+    scopes TEXT NOT NULL,
+    created_by BIGINT NULL,
+    created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP NULL DEFAULT NULL,
+    disabled_at TIMESTAMP NULL DEFAULT NULL
+);
+
+-- a client can hold several secrets while one is rotated out, only their sha256 is stored
+CREATE TABLE IF NOT EXISTS oauth_client_secrets (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    client_id BIGINT NOT NULL,
+    secret_hash CHAR(64) NOT NULL UNIQUE,
+    hint VARCHAR(8) NOT NULL,
+    created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NULL DEFAULT NULL,
+    last_used_at TIMESTAMP NULL DEFAULT NULL,
+    FOREIGN KEY (client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE
+);
+
+-- managing clients is its own module, admin gets all of it
+INSERT IGNORE INTO modules (name) VALUES ('client');
+
+INSERT IGNORE INTO permissions (module_id, action, description)
+SELECT m.id, a.action, CONCAT(a.action, ' ', m.name)
+FROM modules m
+CROSS JOIN (
+    SELECT 'create' AS action UNION ALL
+    SELECT 'read' UNION ALL
+    SELECT 'update' UNION ALL
+    SELECT 'delete'
+) a
+WHERE m.name = 'client';
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p
+JOIN modules m ON m.id = p.module_id
+WHERE r.name = 'admin' AND m.name = 'client';
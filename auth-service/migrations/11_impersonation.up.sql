@@ -0,0 +1,48 @@
+-- an admin acting as a user; the id is the sid of the impersonation token
+CREATE TABLE IF NOT EXISTS impersonations (
+    id VARCHAR(64) PRIMARY KEY,
+    admin_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    reason VARCHAR(500) NOT NULL,
+    started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_impersonations_user (user_id),
+    INDEX idx_impersonations_admin (admin_id)
+);
+
+-- every request made with an impersonation token, reported by the service that served it
+CREATE TABLE IF NOT EXISTS impersonation_audit (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    impersonation_id VARCHAR(64) NOT NULL,
+    admin_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    service VARCHAR(50) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path VARCHAR(1000) NOT NULL,
+    status INT NOT NULL,
+    ip VARCHAR(64),
+    created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_impersonation_audit (impersonation_id)
+);
+
+-- starting an impersonation is its own permission, only admin has it
+INSERT IGNORE INTO modules (name) VALUES ('impersonation');
+
+INSERT IGNORE INTO permissions (module_id, action, description)
+SELECT m.id, a.action, CONCAT(a.action, ' ', m.name)
+FROM modules m
+CROSS JOIN (
+    SELECT 'create' AS action UNION ALL
+    SELECT 'read' UNION ALL
+    SELECT 'update' UNION ALL
+    SELECT 'delete'
+) a
+WHERE m.name = 'impersonation';
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p
+JOIN modules m ON m.id = p.module_id
+WHERE r.name = 'admin' AND m.name = 'impersonation';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS erasure_jobs;
+ALTER TABLE users DROP COLUMN erased_at;
@@ -0,0 +1,17 @@
+-- set once the personal data of a user has been erased in every service
+ALTER TABLE users ADD COLUMN erased_at TIMESTAMP NULL DEFAULT NULL;
+
+-- erasure requests, worked off in the background because they span every service
+CREATE TABLE IF NOT EXISTS erasure_jobs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    requested_by BIGINT NOT NULL,
+    status ENUM('pending', 'running', 'done', 'failed') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_erasure_jobs_user (user_id),
+    INDEX idx_erasure_jobs_status (status)
+);
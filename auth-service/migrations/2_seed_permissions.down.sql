@@ -0,0 +1,4 @@
+DELETE FROM role_permissions;
+DELETE FROM permissions;
+DELETE FROM modules;
+ALTER TABLE permissions DROP INDEX uniq_module_action;
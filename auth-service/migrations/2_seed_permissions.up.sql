@@ -0,0 +1,38 @@
+-- one permission per module/action pair
+ALTER TABLE permissions ADD UNIQUE KEY uniq_module_action (module_id, action);
+
+-- Seed modules
+INSERT IGNORE INTO modules (name) VALUES
+('user'),
+('role'),
+('category'),
+('product'),
+('cart'),
+('order');
+
+-- Seed CRUD permissions for every module
+INSERT IGNORE INTO permissions (module_id, action, description)
+SELECT m.id, a.action, CONCAT(a.action, ' ', m.name)
+FROM modules m
+CROSS JOIN (
+    SELECT 'create' AS action UNION ALL
+    SELECT 'read' UNION ALL
+    SELECT 'update' UNION ALL
+    SELECT 'delete'
+) a;
+
+-- admin gets everything
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name = 'admin';
+
+-- customers manage their own cart and orders
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p
+JOIN modules m ON m.id = p.module_id
+WHERE r.name = 'customer'
+  AND (m.name = 'cart' OR (m.name = 'order' AND p.action IN ('create', 'read')));
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS signing_keys;
+DELETE FROM modules WHERE name = 'key';
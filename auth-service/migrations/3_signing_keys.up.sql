@@ -0,0 +1,30 @@
+-- asymmetric JWT signing keys, the newest non retired key signs new tokens
+CREATE TABLE IF NOT EXISTS signing_keys (
+    kid VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(10) NOT NULL,
+    private_key TEXT NOT NULL,
+    public_key TEXT NOT NULL,
+    created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    retired_at TIMESTAMP NULL DEFAULT NULL
+);
+
+-- key management is its own module so it can be granted separately
+INSERT IGNORE INTO modules (name) VALUES ('key');
+
+INSERT IGNORE INTO permissions (module_id, action, description)
+SELECT m.id, a.action, CONCAT(a.action, ' ', m.name)
+FROM modules m
+CROSS JOIN (
+    SELECT 'create' AS action UNION ALL
+    SELECT 'read' UNION ALL
+    SELECT 'update' UNION ALL
+    SELECT 'delete'
+) a
+WHERE m.name = 'key';
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+JOIN modules m ON m.id = p.module_id
+WHERE r.name = 'admin' AND m.name = 'key';
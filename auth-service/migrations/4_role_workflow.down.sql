@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS role_changes;
+DROP TABLE IF EXISTS role_requests;
+DROP TABLE IF EXISTS role_invites;
@@ -0,0 +1,38 @@
+-- signed invitations granting a role to an email address
+CREATE TABLE IF NOT EXISTS role_invites (
+    id VARCHAR(64) PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    role_id INT NOT NULL,
+    invited_by BIGINT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_by BIGINT NULL DEFAULT NULL,
+    accepted_at TIMESTAMP NULL DEFAULT NULL,
+    created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
+);
+
+-- users asking for a role, decided by an admin
+CREATE TABLE IF NOT EXISTS role_requests (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    role_id INT NOT NULL,
+    reason TEXT,
+    status ENUM('pending', 'approved', 'rejected') NOT NULL DEFAULT 'pending',
+    decided_by BIGINT NULL DEFAULT NULL,
+    decided_at TIMESTAMP NULL DEFAULT NULL,
+    created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
+);
+
+-- history of every role change
+CREATE TABLE IF NOT EXISTS role_changes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    old_role_id BIGINT NULL,
+    new_role_id BIGINT NOT NULL,
+    changed_by BIGINT NULL,
+    source VARCHAR(50) NOT NULL, -- invite, request, admin
+    created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_role_changes_user (user_id)
+);
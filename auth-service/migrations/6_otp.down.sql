@@ -0,0 +1,9 @@
+UPDATE users SET otp = NULL;
+
+ALTER TABLE users
+    DROP COLUMN phone_verified,
+    DROP COLUMN otp_attempts,
+    DROP COLUMN otp_expires_at,
+    DROP COLUMN otp_channel,
+    DROP COLUMN otp_purpose,
+    MODIFY otp VARCHAR(10);
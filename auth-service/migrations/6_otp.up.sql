@@ -0,0 +1,9 @@
+-- one time codes are stored hashed, with what they are for, where they were sent, when they expire
+-- and how many wrong guesses were made
+ALTER TABLE users
+    MODIFY otp VARCHAR(255) NULL,
+    ADD COLUMN otp_purpose VARCHAR(20) NULL AFTER otp,
+    ADD COLUMN otp_channel VARCHAR(10) NULL AFTER otp_purpose,
+    ADD COLUMN otp_expires_at TIMESTAMP NULL DEFAULT NULL AFTER otp_channel,
+    ADD COLUMN otp_attempts INT NOT NULL DEFAULT 0 AFTER otp_expires_at,
+    ADD COLUMN phone_verified TINYINT(1) DEFAULT 0 AFTER phone;
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP INDEX idx_users_created,
+    DROP COLUMN deleted_at,
+    DROP COLUMN disabled_at;
@@ -0,0 +1,6 @@
+-- disabled accounts can not sign in until an admin enables them again, deleted accounts are kept
+-- for the order history but never come back
+ALTER TABLE users
+    ADD COLUMN disabled_at TIMESTAMP NULL DEFAULT NULL AFTER role_id,
+    ADD COLUMN deleted_at TIMESTAMP NULL DEFAULT NULL AFTER disabled_at,
+    ADD INDEX idx_users_created (created);
@@ -4,13 +4,29 @@ import (
 	"database/sql"
 	"net/http"
 	"savannah-store/catalog-service/internal/models"
+	"savannah-store/pkg/mq"
 	"strconv"
 
+	"github.com/go-redis/redis"
 	"github.com/labstack/echo/v4"
 )
 
+// categoryHierarchyCTE selects the category given as its parameter and every category below it
+const categoryHierarchyCTE = `
+	WITH RECURSIVE category_hierarchy AS (
+		SELECT id, name
+		FROM categories
+		WHERE id = ?
+
+		UNION ALL
+
+		SELECT c.id, c.name
+		FROM categories c
+		INNER JOIN category_hierarchy ch ON c.parent_id = ch.id
+	)`
+
 // CreateCategory inserts a new category
-func CreateCategory(c echo.Context, db *sql.DB) error {
+func CreateCategory(c echo.Context, db *sql.DB, rdb *redis.Client) error {
 
 	req := new(models.CategoryRequest)
 	if err := c.Bind(req); err != nil {
@@ -47,6 +63,7 @@ func CreateCategory(c echo.Context, db *sql.DB) error {
 	}
 
 	categoryID, _ := res.LastInsertId()
+	invalidateCategoryStats(rdb)
 	return c.JSON(http.StatusCreated, echo.Map{
 		"id":        categoryID,
 		"name":      req.Name,
@@ -63,18 +80,7 @@ func GetAveragePrice(c echo.Context, db *sql.DB) error {
 	}
 
 	// Recursive query with category name
-	query := `
-	WITH RECURSIVE category_hierarchy AS (
-		SELECT id, name
-		FROM categories
-		WHERE id = ?
-
-		UNION ALL
-
-		SELECT c.id, c.name
-		FROM categories c
-		INNER JOIN category_hierarchy ch ON c.parent_id = ch.id
-	)
+	query := categoryHierarchyCTE + `
 	SELECT (SELECT name FROM categories WHERE id = ?) AS category_name,
 	       AVG(p.price) AS avg_price
 	FROM products p
@@ -132,26 +138,48 @@ func ViewCategories(c echo.Context, db *sql.DB) error {
 	return c.JSON(http.StatusOK, categories)
 }
 
-// UpdateCategory modifies a category
-func UpdateCategory(c echo.Context, db *sql.DB) error {
+// UpdateCategory modifies a category, a new parent goes through the same checks as MoveCategory
+func UpdateCategory(c echo.Context, db *sql.DB, rdb *redis.Client, pub *mq.Publisher) error {
 
-	id := c.Param("id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid category id"})
+	}
 	req := new(models.CategoryRequest)
 	if err := c.Bind(req); err != nil {
 		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
 	}
 
-	query := `UPDATE categories SET name = ?, parent_id = ? WHERE id = ?`
-	_, err := db.Exec(query, req.Name, req.ParentID, id)
+	ctx := c.Request().Context()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
 	}
+	defer tx.Rollback()
+
+	moved, httpErr := moveCategory(ctx, tx, id, topLevelIfZero(req.ParentID), true)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
 
+	query := `UPDATE categories SET name = ? WHERE id = ?`
+	_, err = tx.ExecContext(ctx, query, req.Name, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	invalidateCategoryStats(rdb)
+	if !sameParent(moved.OldParentID, moved.NewParentID) {
+		publishEvent(pub, EventCategoryMoved, moved)
+	}
 	return c.JSON(http.StatusOK, echo.Map{"message": "category updated"})
 }
 
 // DeleteCategory removes a category
-func DeleteCategory(c echo.Context, db *sql.DB) error {
+func DeleteCategory(c echo.Context, db *sql.DB, rdb *redis.Client) error {
 	id := c.Param("id")
 
 	// Check if category has children or products before deleting
@@ -174,57 +202,44 @@ func DeleteCategory(c echo.Context, db *sql.DB) error {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
 	}
 
+	invalidateCategoryStats(rdb)
 	return c.JSON(http.StatusOK, echo.Map{"message": "category deleted"})
 }
 
 // CreateProduct inserts a new product
-func CreateProduct(c echo.Context, db *sql.DB) error {
+func CreateProduct(c echo.Context, db *sql.DB, rdb *redis.Client) error {
 
 	req := new(models.ProductRequest)
 	if err := c.Bind(req); err != nil {
 		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
 	}
 
-	query := `INSERT INTO products (name, price, category_id) VALUES (?, ?, ?)`
-	res, err := db.Exec(query, req.Name, req.Price, req.CategoryID)
+	ctx := c.Request().Context()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
 	}
+	defer tx.Rollback()
 
-	id, _ := res.LastInsertId()
-	return c.JSON(http.StatusCreated, echo.Map{"id": id, "name": req.Name, "price": req.Price, "category_id": req.CategoryID})
-}
-
-// ViewProducts retrieves all products
-func ViewProducts(c echo.Context, db *sql.DB) error {
-	rows, err := db.Query(`SELECT id, name, price, category_id FROM products`)
+	query := `INSERT INTO products (name, price, category_id) VALUES (?, ?, ?)`
+	res, err := tx.ExecContext(ctx, query, req.Name, req.Price, req.CategoryID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
 	}
-	defer rows.Close()
 
-	var products []map[string]interface{}
-	for rows.Next() {
-		var id int64
-		var name string
-		var price float64
-		var categoryID int64
-		if err := rows.Scan(&id, &name, &price, &categoryID); err != nil {
-			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
-		}
-		products = append(products, echo.Map{
-			"id":          id,
-			"name":        name,
-			"price":       price,
-			"category_id": categoryID,
-		})
+	id, _ := res.LastInsertId()
+	if err := saveProductAttributes(ctx, tx, id, req.Attributes); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
 	}
-
-	return c.JSON(http.StatusOK, products)
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	invalidateCategoryStats(rdb)
+	return c.JSON(http.StatusCreated, echo.Map{"id": id, "name": req.Name, "price": req.Price, "category_id": req.CategoryID, "attributes": req.Attributes})
 }
 
 // UpdateProduct modifies a product
-func UpdateProduct(c echo.Context, db *sql.DB) error {
+func UpdateProduct(c echo.Context, db *sql.DB, rdb *redis.Client) error {
 
 	id := c.Param("id")
 	req := new(models.ProductUpdateRequest)
@@ -232,17 +247,38 @@ func UpdateProduct(c echo.Context, db *sql.DB) error {
 		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
 	}
 
+	ctx := c.Request().Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer tx.Rollback()
+
 	query := `UPDATE products SET name = ?, price = ?, category_id = ? WHERE id = ?`
-	_, err := db.Exec(query, req.Name, req.Price, req.CategoryID, id)
+	_, err = tx.ExecContext(ctx, query, req.Name, req.Price, req.CategoryID, id)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
 	}
 
+	if req.Attributes != nil {
+		productID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid product id"})
+		}
+		if err := saveProductAttributes(ctx, tx, productID, req.Attributes); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	invalidateCategoryStats(rdb)
 	return c.JSON(http.StatusOK, echo.Map{"message": "product updated"})
 }
 
 // DeleteProduct removes a product
-func DeleteProduct(c echo.Context, db *sql.DB) error {
+func DeleteProduct(c echo.Context, db *sql.DB, rdb *redis.Client) error {
 	id := c.Param("id")
 
 	_, err := db.Exec(`DELETE FROM products WHERE id = ?`, id)
@@ -250,5 +286,6 @@ func DeleteProduct(c echo.Context, db *sql.DB) error {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
 	}
 
+	invalidateCategoryStats(rdb)
 	return c.JSON(http.StatusOK, echo.Map{"message": "product deleted"})
 }
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"savannah-store/catalog-service/internal/models"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxCategoryDepth bounds the recursive category queries, no real hierarchy gets near it
+const maxCategoryDepth = 64
+
+// CategoryTree returns the categories as nested nodes, from the top level or below ?root, at most ?depth levels deep
+func CategoryTree(c echo.Context, db *sql.DB) error {
+	var rootID int64
+	if v := c.QueryParam("root"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 1 {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid root"})
+		}
+		rootID = id
+	}
+	depth, httpErr := categoryDepth(c)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+
+	nodes, err := categorySubtree(c.Request().Context(), db, rootID, depth)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if rootID != 0 && len(nodes) == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "category not found"})
+	}
+
+	// nodes come level by level, so every parent is indexed before its children
+	byID := map[int64]*models.CategoryNode{}
+	roots := []*models.CategoryNode{}
+	for _, n := range nodes {
+		byID[n.ID] = n
+		if n.Depth == 0 {
+			roots = append(roots, n)
+		} else if parent, ok := byID[*n.ParentID]; ok {
+			parent.Children = append(parent.Children, n)
+		}
+	}
+	return c.JSON(http.StatusOK, roots)
+}
+
+// CategoryAncestors returns the breadcrumb of the category :id, from its top level category down to itself
+func CategoryAncestors(c echo.Context, db *sql.DB) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid category id"})
+	}
+
+	rows, err := db.QueryContext(c.Request().Context(), `
+		WITH RECURSIVE category_path AS (
+			SELECT id, name, parent_id, 0 AS level
+			FROM categories
+			WHERE id = ?
+
+			UNION ALL
+
+			SELECT c.id, c.name, c.parent_id, cp.level + 1
+			FROM categories c
+			INNER JOIN category_path cp ON c.id = cp.parent_id
+			WHERE cp.level < ?
+		)
+		SELECT id, name, parent_id, level FROM category_path ORDER BY level DESC`, id, maxCategoryDepth)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer rows.Close()
+
+	nodes, err := scanCategoryNodes(rows)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if len(nodes) == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "category not found"})
+	}
+	// depth counts down from the top level category
+	for i, n := range nodes {
+		n.Depth = i
+	}
+	return c.JSON(http.StatusOK, nodes)
+}
+
+// CategoryDescendants returns every category below :id as a flat list, level by level, at most ?depth levels deep
+func CategoryDescendants(c echo.Context, db *sql.DB) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid category id"})
+	}
+	depth, httpErr := categoryDepth(c)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+
+	nodes, err := categorySubtree(c.Request().Context(), db, id, depth)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if len(nodes) == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "category not found"})
+	}
+	return c.JSON(http.StatusOK, nodes[1:])
+}
+
+func categoryDepth(c echo.Context) (int, *echo.HTTPError) {
+	v := c.QueryParam("depth")
+	if v == "" {
+		return maxCategoryDepth, nil
+	}
+	depth, err := strconv.Atoi(v)
+	if err != nil || depth < 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid depth")
+	}
+	if depth > maxCategoryDepth {
+		depth = maxCategoryDepth
+	}
+	return depth, nil
+}
+
+// categorySubtree returns the category rootID, or every top level category when rootID is 0, and the categories
+// below down to depth levels, ordered by level and name
+func categorySubtree(ctx context.Context, db *sql.DB, rootID int64, depth int) ([]*models.CategoryNode, error) {
+	start, args := `parent_id IS NULL`, []interface{}{}
+	if rootID != 0 {
+		start, args = `id = ?`, append(args, rootID)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		WITH RECURSIVE category_tree AS (
+			SELECT id, name, parent_id, 0 AS depth
+			FROM categories
+			WHERE `+start+`
+
+			UNION ALL
+
+			SELECT c.id, c.name, c.parent_id, ct.depth + 1
+			FROM categories c
+			INNER JOIN category_tree ct ON c.parent_id = ct.id
+			WHERE ct.depth < ?
+		)
+		SELECT id, name, parent_id, depth FROM category_tree ORDER BY depth, name`, append(args, depth)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCategoryNodes(rows)
+}
+
+func scanCategoryNodes(rows *sql.Rows) ([]*models.CategoryNode, error) {
+	nodes := []*models.CategoryNode{}
+	for rows.Next() {
+		var (
+			n        models.CategoryNode
+			parentID sql.NullInt64
+		)
+		if err := rows.Scan(&n.ID, &n.Name, &parentID, &n.Depth); err != nil {
+			return nil, err
+		}
+		if parentID.Valid {
+			n.ParentID = &parentID.Int64
+		}
+		nodes = append(nodes, &n)
+	}
+	return nodes, rows.Err()
+}
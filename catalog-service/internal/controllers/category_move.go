@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"savannah-store/catalog-service/internal/models"
+	"savannah-store/pkg/mq"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+)
+
+// MoveCategory gives the category :id a new parent, or makes it top level, together with everything below it.
+// With subtree false only the category moves and its children take its old place.
+func MoveCategory(c echo.Context, db *sql.DB, rdb *redis.Client, pub *mq.Publisher) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid category id"})
+	}
+	req := new(models.CategoryMoveRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+	subtree := req.Subtree == nil || *req.Subtree
+
+	ctx := c.Request().Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer tx.Rollback()
+
+	event, httpErr := moveCategory(ctx, tx, id, topLevelIfZero(req.ParentID), subtree)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	invalidateCategoryStats(rdb)
+	publishEvent(pub, EventCategoryMoved, event)
+	return c.JSON(http.StatusOK, event)
+}
+
+// MergeCategory moves the products and subcategories of the category :id into the category in the body
+// and deletes :id
+func MergeCategory(c echo.Context, db *sql.DB, rdb *redis.Client, pub *mq.Publisher) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid category id"})
+	}
+	req := new(models.CategoryMergeRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+	if req.Into == 0 {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "into is required"})
+	}
+	if req.Into == id {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "a category can not be merged into itself"})
+	}
+
+	ctx := c.Request().Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer tx.Rollback()
+
+	parents, err := lockCategories(ctx, tx, id, req.Into)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if _, ok := parents[id]; !ok {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "category not found"})
+	}
+	if _, ok := parents[req.Into]; !ok {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "target category not found"})
+	}
+	below, err := lockAncestors(ctx, tx, req.Into, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if below {
+		return c.JSON(http.StatusConflict, echo.Map{"error": "a category can not be merged into one of its subcategories"})
+	}
+
+	event := &models.CategoriesMergedEvent{SourceID: id, TargetID: req.Into}
+	res, err := tx.ExecContext(ctx, `UPDATE products SET category_id = ? WHERE category_id = ?`, req.Into, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	event.ProductsMoved, _ = res.RowsAffected()
+	res, err = tx.ExecContext(ctx, `UPDATE categories SET parent_id = ? WHERE parent_id = ?`, req.Into, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	event.ChildrenMoved, _ = res.RowsAffected()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM categories WHERE id = ?`, id); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	event.MergedAt = time.Now().UTC()
+	invalidateCategoryStats(rdb)
+	publishEvent(pub, EventCategoryMerged, event)
+	return c.JSON(http.StatusOK, event)
+}
+
+// moveCategory re-parents the category id inside tx. It refuses to put a category below itself, which
+// would send the recursive category queries into a loop, and locks every category above the new parent so
+// concurrent moves can not close a loop together either.
+func moveCategory(ctx context.Context, tx *sql.Tx, id int64, parentID *int64, subtree bool) (*models.CategoryMovedEvent, *echo.HTTPError) {
+	ids := []int64{id}
+	if parentID != nil {
+		if *parentID == id {
+			return nil, echo.NewHTTPError(http.StatusConflict, "a category can not be its own parent")
+		}
+		ids = append(ids, *parentID)
+	}
+	parents, err := lockCategories(ctx, tx, ids...)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	oldParentID, ok := parents[id]
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "category not found")
+	}
+	if parentID != nil {
+		if _, ok := parents[*parentID]; !ok {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "parent category not found")
+		}
+	}
+
+	event := &models.CategoryMovedEvent{CategoryID: id, OldParentID: oldParentID, NewParentID: parentID, Subtree: subtree}
+	if !subtree {
+		// the children take the category's place first, so it may even move below one of them
+		res, err := tx.ExecContext(ctx, `UPDATE categories SET parent_id = ? WHERE parent_id = ?`, oldParentID, id)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		event.ChildrenReparented, _ = res.RowsAffected()
+	}
+
+	if parentID != nil {
+		below, err := lockAncestors(ctx, tx, *parentID, id)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if below {
+			return nil, echo.NewHTTPError(http.StatusConflict, "a category can not move below one of its subcategories")
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE categories SET parent_id = ? WHERE id = ?`, parentID, id); err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	event.MovedAt = time.Now().UTC()
+	return event, nil
+}
+
+// lockCategories locks the rows of the categories ids for the rest of tx and returns the parent of each
+// one found, concurrent moves and merges of the same categories queue up
+func lockCategories(ctx context.Context, tx *sql.Tx, ids ...int64) (map[int64]*int64, error) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT id, parent_id FROM categories WHERE id IN (?`+
+		strings.Repeat(", ?", len(ids)-1)+`) ORDER BY id FOR UPDATE`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parents := map[int64]*int64{}
+	for rows.Next() {
+		var (
+			id       int64
+			parentID sql.NullInt64
+		)
+		if err := rows.Scan(&id, &parentID); err != nil {
+			return nil, err
+		}
+		parents[id] = nil
+		if parentID.Valid {
+			parents[id] = &parentID.Int64
+		}
+	}
+	return parents, rows.Err()
+}
+
+// lockAncestors walks up from the category id, locking it and every category above it for the rest of tx, and
+// tells whether movingID is among them. A concurrent move that could close a loop with the one in tx has to
+// re-parent one of those categories, so it waits for tx and its own walk then runs into the new parent.
+func lockAncestors(ctx context.Context, tx *sql.Tx, id, movingID int64) (bool, error) {
+	for depth := 0; depth <= maxCategoryDepth; depth++ {
+		if id == movingID {
+			return true, nil
+		}
+		var parentID sql.NullInt64
+		err := tx.QueryRowContext(ctx, `SELECT parent_id FROM categories WHERE id = ? FOR UPDATE`, id).Scan(&parentID)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !parentID.Valid {
+			return false, nil
+		}
+		id = parentID.Int64
+	}
+	return false, errors.New("category hierarchy is deeper than " + strconv.Itoa(maxCategoryDepth) + " levels")
+}
+
+// topLevelIfZero maps the parent_id 0 clients send for top level categories to NULL
+func topLevelIfZero(parentID *int64) *int64 {
+	if parentID == nil || *parentID == 0 {
+		return nil
+	}
+	return parentID
+}
+
+func sameParent(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"savannah-store/catalog-service/internal/library"
+	"savannah-store/catalog-service/internal/models"
+	"savannah-store/pkg/redisx"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// categoryStatsCacheSeconds is how long statistics are served from redis. Catalog edits drop them at once,
+	// new orders are not announced to the catalog and show up in the sales after at most this long.
+	categoryStatsCacheSeconds = 300
+	// categoryStatsVersionKey is bumped on every catalog edit, the cache keys carry it so older entries are never read again
+	categoryStatsVersionKey = "category-stats:version"
+	defaultPercentiles      = "25,75,90"
+	maxPercentiles          = 10
+)
+
+// categoryBranchesCTE pairs every category of the subtree below ? (at most ? levels deep) with the direct child
+// of the root it sits under, the root's own id for the root itself
+const categoryBranchesCTE = `
+	WITH RECURSIVE category_branches AS (
+		SELECT id, id AS branch_id, 0 AS depth
+		FROM categories
+		WHERE id = ?
+
+		UNION ALL
+
+		SELECT c.id, IF(cb.depth = 0, c.id, cb.branch_id), cb.depth + 1
+		FROM categories c
+		INNER JOIN category_branches cb ON c.parent_id = cb.id
+		WHERE cb.depth < ?
+	)`
+
+// CategoryStats returns the product count, price spread (?percentiles, 25,75,90 by default) and, when
+// ORDER_SERVICE_URL is set, the units sold and revenue between ?from and ?to (inclusive dates) of the category :id
+// and each of its subcategories
+func CategoryStats(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid category id"})
+	}
+	percentiles, httpErr := statsPercentiles(c.QueryParam("percentiles"))
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+	from, to, httpErr := statsWindow(c)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+
+	version, err := redisx.Get(rdb, categoryStatsVersionKey)
+	if err != nil {
+		version = "0"
+	}
+	sum := sha256.Sum256([]byte(c.QueryParams().Encode()))
+	key := "category-stats:" + version + ":" + strconv.FormatInt(id, 10) + ":" + hex.EncodeToString(sum[:])
+	if data, err := redisx.Get(rdb, key); err == nil {
+		return c.JSONBlob(http.StatusOK, []byte(data))
+	}
+
+	ctx := c.Request().Context()
+	stats := &models.CategoryStats{Children: []models.SubtreeStats{}}
+	stats.CategoryID = id
+	err = db.QueryRowContext(ctx, `SELECT name FROM categories WHERE id = ?`, id).Scan(&stats.CategoryName)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "category not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if from != "" {
+		stats.From = &from
+	}
+	if to != "" {
+		stats.To = &to
+	}
+
+	branches := map[int64]*models.SubtreeStats{id: &stats.SubtreeStats}
+	rows, err := db.QueryContext(ctx, `SELECT id, name FROM categories WHERE parent_id = ? ORDER BY name`, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	for rows.Next() {
+		var child models.SubtreeStats
+		if err := rows.Scan(&child.CategoryID, &child.CategoryName); err != nil {
+			rows.Close()
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		stats.Children = append(stats.Children, child)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	for i := range stats.Children {
+		branches[stats.Children[i].CategoryID] = &stats.Children[i]
+	}
+
+	productBranches, err := branchPrices(ctx, db, id, branches, percentiles)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if library.OrderServiceConfigured() {
+		// sales are an extra, the catalog figures are still served when order-service can not be reached
+		if err := branchSales(ctx, id, branches, productBranches, from, to); err != nil {
+			log.Printf("failed to read sales of category %d: %v", id, err)
+			stats.SalesUnavailable = true
+		}
+	}
+
+	// figures without the sales are not cached, the next request asks order-service again
+	if data, err := json.Marshal(stats); err == nil && !stats.SalesUnavailable {
+		if err := redisx.SetWithExpiry(rdb, key, string(data), categoryStatsCacheSeconds); err != nil {
+			log.Printf("failed to cache category stats: %v", err)
+		}
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+// branchPrices fills in the price figures of every branch, the root counting the products of all of them, and
+// returns the branch of every product
+func branchPrices(ctx context.Context, db *sql.DB, rootID int64, branches map[int64]*models.SubtreeStats, percentiles []float64) (map[int64]int64, error) {
+	rows, err := db.QueryContext(ctx, categoryBranchesCTE+`
+		SELECT cb.branch_id, p.id, p.price
+		FROM products p
+		INNER JOIN category_branches cb ON cb.id = p.category_id
+		ORDER BY p.price`, rootID, maxCategoryDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// prices come sorted, so every slice stays sorted too
+	prices := map[int64][]float64{}
+	productBranches := map[int64]int64{}
+	for rows.Next() {
+		var (
+			branchID, productID int64
+			price               float64
+		)
+		if err := rows.Scan(&branchID, &productID, &price); err != nil {
+			return nil, err
+		}
+		productBranches[productID] = branchID
+		prices[branchID] = append(prices[branchID], price)
+		if branchID != rootID {
+			prices[rootID] = append(prices[rootID], price)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for branchID, s := range branches {
+		p := prices[branchID]
+		s.ProductCount = len(p)
+		s.Percentiles = map[string]float64{}
+		for _, pct := range percentiles {
+			s.Percentiles["p"+strconv.FormatFloat(pct, 'f', -1, 64)] = percentile(p, pct)
+		}
+		if len(p) == 0 {
+			continue
+		}
+		var total float64
+		for _, v := range p {
+			total += v
+		}
+		s.MinPrice, s.MaxPrice = p[0], p[len(p)-1]
+		s.AveragePrice = roundCents(total / float64(len(p)))
+		s.MedianPrice = percentile(p, 50)
+	}
+	return productBranches, nil
+}
+
+// branchSales adds up the order-service sales of the products of every branch between the inclusive dates from
+// and to, empty for an open side. A product counts towards the category it is filed under now.
+func branchSales(ctx context.Context, rootID int64, branches map[int64]*models.SubtreeStats, productBranches map[int64]int64, from, to string) error {
+	productIDs := make([]int64, 0, len(productBranches))
+	for id := range productBranches {
+		productIDs = append(productIDs, id)
+	}
+	sales, err := library.ProductSales(ctx, productIDs, from, to)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range branches {
+		s.Sales = &models.CategorySales{}
+	}
+	root := branches[rootID].Sales
+	for productID, s := range sales {
+		branchID, ok := productBranches[productID]
+		if !ok {
+			continue
+		}
+		if branchID != rootID {
+			branch := branches[branchID].Sales
+			branch.UnitsSold += s.UnitsSold
+			branch.Revenue = roundCents(branch.Revenue + s.Revenue)
+		}
+		root.UnitsSold += s.UnitsSold
+		root.Revenue = roundCents(root.Revenue + s.Revenue)
+	}
+	return nil
+}
+
+// percentile interpolates the pct percentile between the closest ranks of the sorted prices, 0 without prices
+func percentile(sorted []float64, pct float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := pct / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return roundCents(sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo)))
+}
+
+func statsPercentiles(v string) ([]float64, *echo.HTTPError) {
+	if v == "" {
+		v = defaultPercentiles
+	}
+	seen := map[float64]bool{}
+	var out []float64
+	for _, s := range strings.Split(v, ",") {
+		pct, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || pct <= 0 || pct >= 100 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "percentiles must be numbers between 0 and 100")
+		}
+		if !seen[pct] {
+			seen[pct] = true
+			out = append(out, pct)
+		}
+	}
+	if len(out) > maxPercentiles {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "at most "+strconv.Itoa(maxPercentiles)+" percentiles")
+	}
+	sort.Float64s(out)
+	return out, nil
+}
+
+// statsWindow checks ?from and ?to, inclusive YYYY-MM-DD dates, either may be empty
+func statsWindow(c echo.Context) (from, to string, httpErr *echo.HTTPError) {
+	from, to = c.QueryParam("from"), c.QueryParam("to")
+	var fromDate, toDate time.Time
+	var err error
+	if from != "" {
+		if fromDate, err = time.Parse(time.DateOnly, from); err != nil {
+			return "", "", echo.NewHTTPError(http.StatusBadRequest, "invalid from, expected YYYY-MM-DD")
+		}
+	}
+	if to != "" {
+		if toDate, err = time.Parse(time.DateOnly, to); err != nil {
+			return "", "", echo.NewHTTPError(http.StatusBadRequest, "invalid to, expected YYYY-MM-DD")
+		}
+	}
+	if from != "" && to != "" && fromDate.After(toDate) {
+		return "", "", echo.NewHTTPError(http.StatusBadRequest, "from is after to")
+	}
+	return from, to, nil
+}
+
+// invalidateCategoryStats drops every cached CategoryStats after a catalog edit, a failure is logged and the
+// entries then expire on their own
+func invalidateCategoryStats(rdb *redis.Client) {
+	if _, err := redisx.Incr(rdb, categoryStatsVersionKey); err != nil {
+		log.Printf("failed to invalidate category stats: %v", err)
+	}
+}
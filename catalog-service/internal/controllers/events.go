@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"log"
+	"savannah-store/pkg/mq"
+)
+
+// routing keys of the events published on the catalog.events exchange, see mq.Envelope
+const (
+	EventCategoryMoved  = "category.moved"
+	EventCategoryMerged = "category.merged"
+)
+
+// publishEvent is called once the change an event describes is committed, a failed publish is logged
+// and does not undo the change
+func publishEvent(pub *mq.Publisher, routing string, payload interface{}) {
+	if err := pub.Publish(routing, payload); err != nil {
+		log.Printf("failed to publish %s: %v", routing, err)
+	}
+}
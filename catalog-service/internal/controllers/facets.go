@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"savannah-store/catalog-service/internal/models"
+	"savannah-store/pkg/redisx"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// facetsCacheSeconds is how long the facets of a filter set are served from redis, catalog edits
+	// show up in the counts after at most this long
+	facetsCacheSeconds = 30
+	// targetPriceBuckets is roughly how many bands the price range is cut into without ?price_interval
+	targetPriceBuckets = 10
+	maxPriceBuckets    = 100
+	// maxAttributeValues caps the values counted per attribute, the most common ones are kept
+	maxAttributeValues = 50
+)
+
+// categoryPathsCTE pairs every category with itself and every category below it, so grouping by
+// ancestor_id rolls the counts of the descendants up into each category. With a rootID only the subtree
+// of that category is walked and the id is returned as the query argument of the CTE.
+func categoryPathsCTE(rootID int64) (string, []interface{}) {
+	seed, args := "", []interface{}{}
+	if rootID != 0 {
+		seed, args = " WHERE id = ?", []interface{}{rootID}
+	}
+	return `
+	WITH RECURSIVE category_paths AS (
+		SELECT id AS ancestor_id, id AS category_id
+		FROM categories` + seed + `
+
+		UNION ALL
+
+		SELECT cp.ancestor_id, c.id
+		FROM category_paths cp
+		INNER JOIN categories c ON c.parent_id = cp.category_id
+	)`, args
+}
+
+// ProductFacets counts the products matching the ViewProducts filters per category (subcategories rolled up),
+// price band (?price_interval wide, picked from the price range when absent) and attribute value. The price and
+// attribute facets ignore their own filter so the alternatives keep their counts, the category facet drills
+// down into the picked category and its subcategories.
+func ProductFacets(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	filter, httpErr := productFilter(c)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+	var interval float64
+	if v := c.QueryParam("price_interval"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n <= 0 {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid price_interval"})
+		}
+		interval = n
+	}
+
+	// url.Values.Encode sorts the params, so the same filter set always hits the same key
+	sum := sha256.Sum256([]byte(c.QueryParams().Encode()))
+	key := "facets:" + hex.EncodeToString(sum[:])
+	if data, err := redisx.Get(rdb, key); err == nil {
+		return c.JSONBlob(http.StatusOK, []byte(data))
+	}
+
+	ctx := c.Request().Context()
+	facets := &models.ProductFacets{}
+	with, cond, args := productConditions(filter)
+	if err := db.QueryRowContext(ctx, with+` SELECT COUNT(*) FROM products p`+cond, args...).Scan(&facets.Total); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	var err error
+	if facets.Categories, err = categoryFacets(ctx, db, filter); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	if facets.Price, httpErr = priceFacet(ctx, db, filter, interval); httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+	if facets.Attributes, err = attributeFacets(ctx, db, filter); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	if data, err := json.Marshal(facets); err == nil {
+		if err := redisx.SetWithExpiry(rdb, key, string(data), facetsCacheSeconds); err != nil {
+			log.Printf("failed to cache facets: %v", err)
+		}
+	}
+	return c.JSON(http.StatusOK, facets)
+}
+
+// categoryFacets returns the category tree with the product counts, below the filtered category if there is one.
+// Categories without products are left out.
+func categoryFacets(ctx context.Context, db *sql.DB, f models.ProductFilter) ([]*models.CategoryFacet, error) {
+	// walking the paths from the filtered category already keeps the products to its subtree
+	with, args := categoryPathsCTE(f.CategoryID)
+	f.CategoryID = 0
+	_, cond, condArgs := productConditions(f)
+	rows, err := db.QueryContext(ctx, with+`
+		SELECT a.id, a.name, a.parent_id, COUNT(*)
+		FROM category_paths cp
+		INNER JOIN categories a ON a.id = cp.ancestor_id
+		INNER JOIN products p ON p.category_id = cp.category_id`+cond+`
+		GROUP BY a.id, a.name, a.parent_id`, append(args, condArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := map[int64]*models.CategoryFacet{}
+	for rows.Next() {
+		var (
+			cat      models.CategoryFacet
+			parentID sql.NullInt64
+		)
+		if err := rows.Scan(&cat.ID, &cat.Name, &parentID, &cat.Count); err != nil {
+			return nil, err
+		}
+		if parentID.Valid {
+			cat.ParentID = &parentID.Int64
+		}
+		byID[cat.ID] = &cat
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// a parent counts at least the products of its children, so every parent of a listed category is listed
+	roots := []*models.CategoryFacet{}
+	for _, cat := range byID {
+		if cat.ParentID != nil {
+			if parent, ok := byID[*cat.ParentID]; ok {
+				parent.Children = append(parent.Children, cat)
+				continue
+			}
+		}
+		roots = append(roots, cat)
+	}
+	sortCategoryFacets(roots)
+	return roots, nil
+}
+
+func sortCategoryFacets(cats []*models.CategoryFacet) {
+	sort.Slice(cats, func(i, j int) bool {
+		if cats[i].Count != cats[j].Count {
+			return cats[i].Count > cats[j].Count
+		}
+		return cats[i].Name < cats[j].Name
+	})
+	for _, cat := range cats {
+		sortCategoryFacets(cat.Children)
+	}
+}
+
+// priceFacet returns the price histogram, bands without products are left out
+func priceFacet(ctx context.Context, db *sql.DB, f models.ProductFilter, interval float64) (models.PriceFacet, *echo.HTTPError) {
+	f.MinPrice, f.MaxPrice = nil, nil
+	with, cond, args := productConditions(f)
+
+	facet := models.PriceFacet{Buckets: []models.PriceBucket{}}
+	err := db.QueryRowContext(ctx, with+` SELECT COALESCE(MIN(p.price), 0), COALESCE(MAX(p.price), 0) FROM products p`+cond, args...).
+		Scan(&facet.Min, &facet.Max)
+	if err != nil {
+		return facet, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	span := facet.Max - facet.Min
+	if interval == 0 {
+		interval = niceInterval(span / targetPriceBuckets)
+	}
+	if span/interval > maxPriceBuckets {
+		return facet, echo.NewHTTPError(http.StatusBadRequest, "price_interval yields more than "+strconv.Itoa(maxPriceBuckets)+" bands")
+	}
+	facet.Interval = interval
+
+	// the interval is a validated number, inlining it keeps the CTE parameter first in args
+	band := "FLOOR(p.price / " + strconv.FormatFloat(interval, 'f', -1, 64) + ")"
+	rows, err := db.QueryContext(ctx, with+` SELECT `+band+` AS band, COUNT(*) FROM products p`+cond+` GROUP BY band ORDER BY band`, args...)
+	if err != nil {
+		return facet, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			n     int64
+			count int
+		)
+		if err := rows.Scan(&n, &count); err != nil {
+			return facet, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		from := roundCents(float64(n) * interval)
+		facet.Buckets = append(facet.Buckets, models.PriceBucket{From: from, To: roundCents(from + interval), Count: count})
+	}
+	if err := rows.Err(); err != nil {
+		return facet, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return facet, nil
+}
+
+// niceInterval rounds raw up to 1, 2 or 5 times a power of ten, at least a cent
+func niceInterval(raw float64) float64 {
+	if raw <= 0.01 {
+		return 0.01
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5} {
+		if raw <= m*magnitude {
+			return roundCents(m * magnitude)
+		}
+	}
+	return roundCents(10 * magnitude)
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// attributeFacets counts the values of every attribute. Attributes without a filter are counted in one query,
+// each filtered attribute is counted with its own filter dropped.
+func attributeFacets(ctx context.Context, db *sql.DB, f models.ProductFilter) (map[string][]models.AttributeValueCount, error) {
+	facets := map[string][]models.AttributeValueCount{}
+
+	with, cond, args := productConditions(f)
+	if len(f.Attributes) > 0 {
+		names := make([]string, 0, len(f.Attributes))
+		for name := range f.Attributes {
+			names = append(names, name)
+			args = append(args, name)
+		}
+		cond = andWhere(cond, `pa.name NOT IN (?`+strings.Repeat(", ?", len(names)-1)+`)`)
+
+		for _, name := range names {
+			others := f
+			others.Attributes = map[string][]string{}
+			for k, v := range f.Attributes {
+				if k != name {
+					others.Attributes[k] = v
+				}
+			}
+			w, c, a := productConditions(others)
+			if err := countAttributeValues(ctx, db, facets, w, andWhere(c, `pa.name = ?`), append(a, name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := countAttributeValues(ctx, db, facets, with, cond, args); err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+func countAttributeValues(ctx context.Context, db *sql.DB, facets map[string][]models.AttributeValueCount, with, cond string, args []interface{}) error {
+	rows, err := db.QueryContext(ctx, with+`
+		SELECT pa.name, pa.value, COUNT(*) AS n
+		FROM products p
+		INNER JOIN product_attributes pa ON pa.product_id = p.id`+cond+`
+		GROUP BY pa.name, pa.value
+		ORDER BY pa.name, n DESC, pa.value`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			name string
+			v    models.AttributeValueCount
+		)
+		if err := rows.Scan(&name, &v.Value, &v.Count); err != nil {
+			return err
+		}
+		if len(facets[name]) < maxAttributeValues {
+			facets[name] = append(facets[name], v)
+		}
+	}
+	return rows.Err()
+}
+
+// andWhere adds expr to a WHERE clause built by productConditions
+func andWhere(cond, expr string) string {
+	if cond == "" {
+		return " WHERE " + expr
+	}
+	return cond + " AND " + expr
+}
@@ -0,0 +1,367 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"savannah-store/catalog-service/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultProductsPerPage = 20
+	maxProductsPerPage     = 100
+)
+
+// productSorts maps the ?sort values to the column ordered by, ties are broken by id in the same direction.
+// newest orders by id alone, ids grow with every insert.
+var productSorts = map[string]struct {
+	column string
+	desc   bool
+}{
+	"price":  {"p.price", false},
+	"-price": {"p.price", true},
+	"name":   {"p.name", false},
+	"-name":  {"p.name", true},
+	"newest": {"", true},
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ViewProducts returns a page of products, filtered by ?category_id (including its subcategories), ?min_price,
+// ?max_price, ?name (prefix) and ?attr=name:value and ordered by ?sort. Pages are picked with ?page and
+// ?per_page or, for stable deep paging, with the ?cursor of the previous page. The response is the bare product
+// array of earlier versions, with the total, the first/prev/next/last pages and the next cursor sent as
+// X-Total-Count, Link and X-Next-Cursor headers; ?format=envelope returns them in a ProductList instead.
+func ViewProducts(c echo.Context, db *sql.DB) error {
+	filter, httpErr := productFilter(c)
+	if httpErr != nil {
+		return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+	}
+
+	list, err := listProducts(c, db, filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	c.Response().Header().Set("X-Total-Count", strconv.Itoa(list.Total))
+	if links := productLinks(c, filter, list); len(links) > 0 {
+		c.Response().Header().Set("Link", strings.Join(links, ", "))
+	}
+	if list.NextCursor != "" {
+		c.Response().Header().Set("X-Next-Cursor", list.NextCursor)
+	}
+
+	if c.QueryParam("format") == "envelope" {
+		return c.JSON(http.StatusOK, list)
+	}
+	return c.JSON(http.StatusOK, list.Products)
+}
+
+func productFilter(c echo.Context) (models.ProductFilter, *echo.HTTPError) {
+	filter := models.ProductFilter{
+		NamePrefix: strings.TrimSpace(c.QueryParam("name")),
+		Sort:       c.QueryParam("sort"),
+		Page:       1,
+		PerPage:    defaultProductsPerPage,
+	}
+
+	if filter.Sort == "" {
+		filter.Sort = "newest"
+	}
+	if _, ok := productSorts[filter.Sort]; !ok {
+		return filter, echo.NewHTTPError(http.StatusBadRequest, "sort must be price, -price, name, -name or newest")
+	}
+	if v := c.QueryParam("category_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 1 {
+			return filter, echo.NewHTTPError(http.StatusBadRequest, "invalid category_id")
+		}
+		filter.CategoryID = id
+	}
+	for param, target := range map[string]**float64{"min_price": &filter.MinPrice, "max_price": &filter.MaxPrice} {
+		if v := c.QueryParam(param); v != "" {
+			price, err := strconv.ParseFloat(v, 64)
+			if err != nil || price < 0 {
+				return filter, echo.NewHTTPError(http.StatusBadRequest, "invalid "+param)
+			}
+			*target = &price
+		}
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return filter, echo.NewHTTPError(http.StatusBadRequest, "min_price must not exceed max_price")
+	}
+	if v := c.QueryParam("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return filter, echo.NewHTTPError(http.StatusBadRequest, "invalid page")
+		}
+		filter.Page = page
+	}
+	if v := c.QueryParam("per_page"); v != "" {
+		perPage, err := strconv.Atoi(v)
+		if err != nil || perPage < 1 {
+			return filter, echo.NewHTTPError(http.StatusBadRequest, "invalid per_page")
+		}
+		if perPage > maxProductsPerPage {
+			perPage = maxProductsPerPage
+		}
+		filter.PerPage = perPage
+	}
+	for _, v := range c.QueryParams()["attr"] {
+		name, value, ok := strings.Cut(v, ":")
+		name, value = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			return filter, echo.NewHTTPError(http.StatusBadRequest, "attr must be name:value")
+		}
+		if filter.Attributes == nil {
+			filter.Attributes = map[string][]string{}
+		}
+		filter.Attributes[name] = append(filter.Attributes[name], value)
+	}
+	if v := c.QueryParam("cursor"); v != "" {
+		cursor, err := decodeProductCursor(v)
+		if err != nil {
+			return filter, echo.NewHTTPError(http.StatusBadRequest, "invalid cursor")
+		}
+		if cursor.Sort != filter.Sort {
+			return filter, echo.NewHTTPError(http.StatusBadRequest, "cursor was issued for sort "+cursor.Sort)
+		}
+		filter.Cursor = cursor
+		filter.Page = 0
+	}
+	return filter, nil
+}
+
+// productConditions turns the filter into the WHERE clause on products p, with holds the CTE it relies on
+func productConditions(f models.ProductFilter) (with, cond string, args []interface{}) {
+	var where []string
+	if f.CategoryID != 0 {
+		with = categoryHierarchyCTE
+		args = append(args, f.CategoryID)
+		where = append(where, "p.category_id IN (SELECT id FROM category_hierarchy)")
+	}
+	if f.MinPrice != nil {
+		where = append(where, "p.price >= ?")
+		args = append(args, *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		where = append(where, "p.price <= ?")
+		args = append(args, *f.MaxPrice)
+	}
+	if f.NamePrefix != "" {
+		where = append(where, "p.name LIKE ?")
+		args = append(args, likeEscaper.Replace(f.NamePrefix)+"%")
+	}
+	names := make([]string, 0, len(f.Attributes))
+	for name := range f.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := f.Attributes[name]
+		where = append(where, `EXISTS (SELECT 1 FROM product_attributes fa WHERE fa.product_id = p.id AND fa.name = ? AND fa.value IN (?`+strings.Repeat(", ?", len(values)-1)+`))`)
+		args = append(args, name)
+		for _, v := range values {
+			args = append(args, v)
+		}
+	}
+	if len(where) > 0 {
+		cond = " WHERE " + strings.Join(where, " AND ")
+	}
+	return with, cond, args
+}
+
+// listProducts runs the filter, it reads one product more than a page to know whether another page follows
+func listProducts(c echo.Context, db *sql.DB, f models.ProductFilter) (*models.ProductList, error) {
+	ctx := c.Request().Context()
+	with, cond, args := productConditions(f)
+
+	list := &models.ProductList{Products: []models.ProductResponse{}, Page: f.Page, PerPage: f.PerPage}
+	if err := db.QueryRowContext(ctx, with+` SELECT COUNT(*) FROM products p`+cond, args...).Scan(&list.Total); err != nil {
+		return nil, err
+	}
+
+	order := productSorts[f.Sort]
+	dir, cmp := "ASC", ">"
+	if order.desc {
+		dir, cmp = "DESC", "<"
+	}
+	orderBy := " ORDER BY p.id " + dir
+	if order.column != "" {
+		orderBy = fmt.Sprintf(" ORDER BY %s %s, p.id %s", order.column, dir, dir)
+	}
+
+	if f.Cursor != nil {
+		var after string
+		switch order.column {
+		case "":
+			after = fmt.Sprintf("p.id %s ?", cmp)
+			args = append(args, f.Cursor.ID)
+		case "p.price":
+			after = fmt.Sprintf("(p.price %[1]s ? OR (p.price = ? AND p.id %[1]s ?))", cmp)
+			args = append(args, f.Cursor.Price, f.Cursor.Price, f.Cursor.ID)
+		case "p.name":
+			after = fmt.Sprintf("(p.name %[1]s ? OR (p.name = ? AND p.id %[1]s ?))", cmp)
+			args = append(args, f.Cursor.Name, f.Cursor.Name, f.Cursor.ID)
+		}
+		cond = andWhere(cond, after)
+	}
+
+	limit := ` LIMIT ?`
+	args = append(args, f.PerPage+1)
+	if f.Cursor == nil {
+		limit += ` OFFSET ?`
+		args = append(args, (f.Page-1)*f.PerPage)
+	}
+
+	rows, err := db.QueryContext(ctx, with+` SELECT p.id, p.name, p.price, COALESCE(p.category_id, 0) FROM products p`+cond+orderBy+limit, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.ProductResponse
+		if err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.CategoryID); err != nil {
+			return nil, err
+		}
+		list.Products = append(list.Products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(list.Products) > f.PerPage {
+		list.Products = list.Products[:f.PerPage]
+		last := list.Products[f.PerPage-1]
+		list.NextCursor = encodeProductCursor(models.ProductCursor{Sort: f.Sort, ID: last.ID, Price: last.Price, Name: last.Name})
+	}
+	if err := loadProductAttributes(ctx, db, list.Products); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// saveProductAttributes replaces the attributes of a product, names are stored in lower case
+func saveProductAttributes(ctx context.Context, tx *sql.Tx, productID int64, attrs map[string]string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM product_attributes WHERE product_id = ?`, productID); err != nil {
+		return err
+	}
+	for name, value := range attrs {
+		name, value = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)
+		if name == "" || value == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO product_attributes (product_id, name, value) VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE value = VALUES(value)`, productID, name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadProductAttributes fills in the attributes of products with one query
+func loadProductAttributes(ctx context.Context, db *sql.DB, products []models.ProductResponse) error {
+	if len(products) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*models.ProductResponse, len(products))
+	args := make([]interface{}, len(products))
+	for i := range products {
+		byID[products[i].ID] = &products[i]
+		args[i] = products[i].ID
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT product_id, name, value FROM product_attributes WHERE product_id IN (?`+
+		strings.Repeat(", ?", len(products)-1)+`)`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id          int64
+			name, value string
+		)
+		if err := rows.Scan(&id, &name, &value); err != nil {
+			return err
+		}
+		p := byID[id]
+		if p.Attributes == nil {
+			p.Attributes = map[string]string{}
+		}
+		p.Attributes[name] = value
+	}
+	return rows.Err()
+}
+
+// productLinks builds the RFC 8288 Link header entries of the listing. Cursor pages only link to the first
+// and the next page, offset pages also to the previous and the last one.
+func productLinks(c echo.Context, f models.ProductFilter, list *models.ProductList) []string {
+	link := func(rel string, set map[string]string) string {
+		return fmt.Sprintf(`<%s>; rel="%s"`, pageURL(c, set), rel)
+	}
+
+	links := []string{link("first", map[string]string{"page": "1", "cursor": ""})}
+	if f.Cursor != nil {
+		if list.NextCursor != "" {
+			links = append(links, link("next", map[string]string{"page": "", "cursor": list.NextCursor}))
+		}
+		return links
+	}
+
+	lastPage := (list.Total + f.PerPage - 1) / f.PerPage
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	if f.Page > 1 {
+		links = append(links, link("prev", map[string]string{"page": strconv.Itoa(f.Page - 1)}))
+	}
+	if f.Page < lastPage {
+		links = append(links, link("next", map[string]string{"page": strconv.Itoa(f.Page + 1)}))
+	}
+	return append(links, link("last", map[string]string{"page": strconv.Itoa(lastPage)}))
+}
+
+// pageURL is the request URL with the query params in set replaced, empty values drop the param
+func pageURL(c echo.Context, set map[string]string) string {
+	q := url.Values{}
+	for k, v := range c.QueryParams() {
+		q[k] = append([]string(nil), v...)
+	}
+	for k, v := range set {
+		if v == "" {
+			q.Del(k)
+		} else {
+			q.Set(k, v)
+		}
+	}
+	return fmt.Sprintf("%s://%s%s?%s", c.Scheme(), c.Request().Host, c.Request().URL.Path, q.Encode())
+}
+
+func encodeProductCursor(cursor models.ProductCursor) string {
+	b, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeProductCursor(s string) (*models.ProductCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	cursor := &models.ProductCursor{}
+	if err := json.Unmarshal(b, cursor); err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"net/http"
+	"savannah-store/catalog-service/internal/models"
+	"savannah-store/catalog-service/internal/search"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultSuggestions = 10
+	maxSuggestions     = 25
+)
+
+// Search returns a page of products matching ?q, most relevant first
+func Search(c echo.Context, idx search.Index) error {
+	q := models.SearchQuery{Text: strings.TrimSpace(c.QueryParam("q")), Page: 1, PerPage: defaultProductsPerPage}
+	if q.Text == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "q is required"})
+	}
+	if v := c.QueryParam("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid page"})
+		}
+		q.Page = page
+	}
+	if v := c.QueryParam("per_page"); v != "" {
+		perPage, err := strconv.Atoi(v)
+		if err != nil || perPage < 1 {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid per_page"})
+		}
+		if perPage > maxProductsPerPage {
+			perPage = maxProductsPerPage
+		}
+		q.PerPage = perPage
+	}
+
+	result, err := idx.Search(c.Request().Context(), q)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// SuggestProducts completes ?q to product names for search as you type, at most ?limit of them
+func SuggestProducts(c echo.Context, idx search.Index) error {
+	prefix := strings.TrimSpace(c.QueryParam("q"))
+	limit := defaultSuggestions
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid limit"})
+		}
+		if n > maxSuggestions {
+			n = maxSuggestions
+		}
+		limit = n
+	}
+
+	names, err := idx.Suggest(c.Request().Context(), prefix, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"query": prefix, "suggestions": names})
+}
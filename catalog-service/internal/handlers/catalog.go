@@ -20,7 +20,7 @@ import (
 // @Failure      400   {object} map[string]string
 // @Router       /catalog/categories [post]
 func (a *App) CreateCategory(c echo.Context) error {
-	return controllers.CreateCategory(c, a.DB)
+	return controllers.CreateCategory(c, a.DB, a.RedisConnection)
 }
 
 // GetAveragePrice godoc
@@ -39,6 +39,24 @@ func (a *App) GetAveragePrice(c echo.Context) error {
 	return controllers.GetAveragePrice(c, a.DB)
 }
 
+// CategoryStats godoc
+// @Summary      Category statistics
+// @Description  Returns the product count, min, max, average, median and percentile prices, and the units sold and revenue of a category and its subcategories, with a breakdown per direct subcategory. Cached in Redis until the catalog changes
+// @Tags         Categories
+// @Produce      json
+// @Param        api-key      header  string  true   "API Key for authentication"
+// @Param        id           path    int     true   "Category ID"
+// @Param        percentiles  query   string  false  "Comma separated percentiles, 25,75,90 by default"
+// @Param        from         query   string  false  "First day of the sales window (YYYY-MM-DD)"
+// @Param        to           query   string  false  "Last day of the sales window (YYYY-MM-DD)"
+// @Success      200  {object} models.CategoryStats
+// @Failure      400  {object} map[string]string
+// @Failure      404  {object} map[string]string
+// @Router       /catalog/categories/{id}/stats [get]
+func (a *App) CategoryStats(c echo.Context) error {
+	return controllers.CategoryStats(c, a.DB, a.RedisConnection)
+}
+
 
 // ViewCategories godoc
 // @Summary      List categories
@@ -51,6 +69,48 @@ func (a *App) ViewCategories(c echo.Context) error {
 	return controllers.ViewCategories(c, a.DB)
 }
 
+// CategoryTree godoc
+// @Summary      Category tree
+// @Description  Returns the categories as nested nodes, from the top level or below a root category
+// @Tags         Catalog
+// @Produce      json
+// @Param        root   query  int  false  "Category the tree starts at"
+// @Param        depth  query  int  false  "Levels below the top, 0 returns the top only"
+// @Success      200  {array} models.CategoryNode
+// @Failure      400  {object} map[string]string
+// @Failure      404  {object} map[string]string
+// @Router       /catalog/categories/tree [get]
+func (a *App) CategoryTree(c echo.Context) error {
+	return controllers.CategoryTree(c, a.DB)
+}
+
+// CategoryAncestors godoc
+// @Summary      Category breadcrumb
+// @Description  Returns the path from the top level category down to the category itself
+// @Tags         Catalog
+// @Produce      json
+// @Param        id  path  int  true  "Category ID"
+// @Success      200  {array} models.CategoryNode
+// @Failure      404  {object} map[string]string
+// @Router       /catalog/categories/{id}/ancestors [get]
+func (a *App) CategoryAncestors(c echo.Context) error {
+	return controllers.CategoryAncestors(c, a.DB)
+}
+
+// CategoryDescendants godoc
+// @Summary      Category descendants
+// @Description  Returns every category below the category as a flat list, level by level
+// @Tags         Catalog
+// @Produce      json
+// @Param        id     path   int  true   "Category ID"
+// @Param        depth  query  int  false  "Levels below the category"
+// @Success      200  {array} models.CategoryNode
+// @Failure      404  {object} map[string]string
+// @Router       /catalog/categories/{id}/descendants [get]
+func (a *App) CategoryDescendants(c echo.Context) error {
+	return controllers.CategoryDescendants(c, a.DB)
+}
+
 // UpdateCategory godoc
 // @Summary      Update category
 // @Description  Updates a catalog category by ID
@@ -62,9 +122,47 @@ func (a *App) ViewCategories(c echo.Context) error {
 // @Param        body  body  models.CategoryUpdateRequest true "Updated category info"
 // @Success      200   {object} models.CategoryResponse
 // @Failure      400   {object} map[string]string
+// @Failure      404   {object} map[string]string
+// @Failure      409   {object} map[string]string
 // @Router       /catalog/categories/{id} [put]
 func (a *App) UpdateCategory(c echo.Context) error {
-	return controllers.UpdateCategory(c, a.DB)
+	return controllers.UpdateCategory(c, a.DB, a.RedisConnection, a.Publisher)
+}
+
+// MoveCategory godoc
+// @Summary      Move category
+// @Description  Moves a category, with its subcategories unless subtree is false, below another category or to the top level (parent_id null or 0). Publishes category.moved
+// @Tags         Catalog
+// @Param        api-key header string true "API Key for authentication"
+// @Accept       json
+// @Produce      json
+// @Param        id    path  int                        true  "Category ID"
+// @Param        body  body  models.CategoryMoveRequest true  "New parent"
+// @Success      200   {object} models.CategoryMovedEvent
+// @Failure      400   {object} map[string]string
+// @Failure      404   {object} map[string]string
+// @Failure      409   {object} map[string]string
+// @Router       /catalog/categories/{id}/move [post]
+func (a *App) MoveCategory(c echo.Context) error {
+	return controllers.MoveCategory(c, a.DB, a.RedisConnection, a.Publisher)
+}
+
+// MergeCategory godoc
+// @Summary      Merge categories
+// @Description  Moves the products and subcategories of a category into another one and deletes it. Publishes category.merged
+// @Tags         Catalog
+// @Param        api-key header string true "API Key for authentication"
+// @Accept       json
+// @Produce      json
+// @Param        id    path  int                         true  "Category ID"
+// @Param        body  body  models.CategoryMergeRequest true  "Category to merge into"
+// @Success      200   {object} models.CategoriesMergedEvent
+// @Failure      400   {object} map[string]string
+// @Failure      404   {object} map[string]string
+// @Failure      409   {object} map[string]string
+// @Router       /catalog/categories/{id}/merge [post]
+func (a *App) MergeCategory(c echo.Context) error {
+	return controllers.MergeCategory(c, a.DB, a.RedisConnection, a.Publisher)
 }
 
 // DeleteCategory godoc
@@ -77,7 +175,7 @@ func (a *App) UpdateCategory(c echo.Context) error {
 // @Failure      404   {object} map[string]string
 // @Router       /catalog/categories/{id} [delete]
 func (a *App) DeleteCategory(c echo.Context) error {
-	return controllers.DeleteCategory(c, a.DB)
+	return controllers.DeleteCategory(c, a.DB, a.RedisConnection)
 }
 
 
@@ -93,15 +191,26 @@ func (a *App) DeleteCategory(c echo.Context) error {
 // @Failure      400   {object} map[string]string
 // @Router       /catalog/products [post]
 func (a *App) CreateProduct(c echo.Context) error {
-	return controllers.CreateProduct(c, a.DB)
+	return controllers.CreateProduct(c, a.DB, a.RedisConnection)
 }
 
 // ViewProducts godoc
 // @Summary      List products
-// @Description  Retrieves all catalog products
+// @Description  Retrieves a page of catalog products, filtered and sorted, as a bare array. The total is sent as X-Total-Count, the neighbouring pages as Link and the next cursor as X-Next-Cursor headers; format=envelope returns them in a models.ProductList instead.
 // @Tags         Catalog
 // @Produce      json
+// @Param        category_id  query  int     false  "Category, including its subcategories"
+// @Param        min_price    query  number  false  "Minimum price"
+// @Param        max_price    query  number  false  "Maximum price"
+// @Param        name         query  string  false  "Name prefix"
+// @Param        sort         query  string  false  "price, -price, name, -name or newest (default)"
+// @Param        page         query  int     false  "Page, default 1"
+// @Param        per_page     query  int     false  "Products per page, default 20, at most 100"
+// @Param        attr         query  string  false  "Attribute filter as name:value, repeatable"
+// @Param        cursor       query  string  false  "next_cursor of the previous page, replaces page"
+// @Param        format       query  string  false  "envelope wraps the page in a models.ProductList"
 // @Success      200  {array} models.ProductResponse
+// @Failure      400  {object} map[string]string
 // @Router       /catalog/products [get]
 func (a *App) ViewProducts(c echo.Context) error {
 	return controllers.ViewProducts(c, a.DB)
@@ -120,7 +229,7 @@ func (a *App) ViewProducts(c echo.Context) error {
 // @Failure      400   {object} map[string]string
 // @Router       /catalog/products/{id} [put]
 func (a *App) UpdateProduct(c echo.Context) error {
-	return controllers.UpdateProduct(c, a.DB)
+	return controllers.UpdateProduct(c, a.DB, a.RedisConnection)
 }
 
 // DeleteProduct godoc
@@ -134,5 +243,23 @@ func (a *App) UpdateProduct(c echo.Context) error {
 // @Failure      404   {object} map[string]string
 // @Router       /catalog/products/{id} [delete]
 func (a *App) DeleteProduct(c echo.Context) error {
-	return controllers.DeleteProduct(c, a.DB)
+	return controllers.DeleteProduct(c, a.DB, a.RedisConnection)
+}
+
+// ProductFacets godoc
+// @Summary      Product facets
+// @Description  Counts the products matching the product listing filters per category (subcategories rolled up into their parents), price band and attribute value. Each facet ignores its own filter.
+// @Tags         Catalog
+// @Produce      json
+// @Param        category_id     query  int     false  "Category, including its subcategories"
+// @Param        min_price       query  number  false  "Minimum price"
+// @Param        max_price       query  number  false  "Maximum price"
+// @Param        name            query  string  false  "Name prefix"
+// @Param        attr            query  string  false  "Attribute filter as name:value, repeatable"
+// @Param        price_interval  query  number  false  "Width of the price bands, picked from the price range when absent"
+// @Success      200  {object} models.ProductFacets
+// @Failure      400  {object} map[string]string
+// @Router       /catalog/products/facets [get]
+func (a *App) ProductFacets(c echo.Context) error {
+	return controllers.ProductFacets(c, a.DB, a.RedisConnection)
 }
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	_ "savannah-store/catalog-service/docs"
 	"savannah-store/catalog-service/internal/logger"
-	"savannah-store/catalog-service/internal/repository"
-	auth "savannah-store/catalog-service/internal/middleware"
+	"savannah-store/catalog-service/internal/search"
+	"savannah-store/pkg/authn"
+	"savannah-store/pkg/dbx"
+	"savannah-store/pkg/mq"
+	"savannah-store/pkg/redisx"
 
 	"github.com/go-redis/redis"
 	"github.com/gorilla/sessions"
@@ -30,18 +33,29 @@ type App struct {
 	E               *echo.Echo
 	RedisConnection *redis.Client
 	RabbitMQConn    *amqp.Connection
+	Auth            *authn.Authenticator
+	SearchIndex     search.Index
+	Publisher       *mq.Publisher
 }
 
 // Initialize initializes the app with predefined configuration
 func (a *App) Initialize() {
 
-	a.RedisConnection = repository.RedisClient()
-	a.RabbitMQConn = repository.GetRabbitMQConnection()
+	a.RedisConnection = redisx.NewClient()
+	a.RabbitMQConn = mq.Dial()
+
+	publisher, err := mq.NewPublisher(a.RabbitMQConn, "catalog.events", "catalog-service")
+	if err != nil {
+		logger.Error("failed to open event publisher %v", err)
+	}
+	a.Publisher = publisher
+	a.Auth = authn.New(authn.Config{Redis: a.RedisConnection, Service: "catalog-service"})
 
 	dbName := os.Getenv("CATALOG_DB_NAME")
 
-	dbO := repository.DbInstance(dbName)
+	dbO := dbx.Open(dbName)
 	a.DB = dbO
+	a.SearchIndex = search.NewMySQLIndex(a.DB)
 
 	a.setRouters()
 
@@ -77,17 +91,28 @@ func (a *App) setRouters() {
 
 	
 	// Category routes
-	a.E.POST("/catalog/categories",a.CreateCategory,auth.RoleMiddleware(a.DB, "admin"))          
+	a.E.POST("/catalog/categories",a.CreateCategory, a.Auth.PermissionMiddleware("category", "create"))          
 	a.E.GET("/catalog/categories", a.ViewCategories)           
-	a.E.PUT("/catalog/categories/:id", a.UpdateCategory,auth.RoleMiddleware(a.DB, "admin")) 
-	a.E.DELETE("/catalog/categories/:id", a.DeleteCategory,auth.RoleMiddleware(a.DB, "admin"))
-	a.E.GET("/categories/:id/average-price",a.GetAveragePrice,auth.RoleMiddleware(a.DB, "admin"))  
+	a.E.GET("/catalog/categories/tree", a.CategoryTree)
+	a.E.GET("/catalog/categories/:id/ancestors", a.CategoryAncestors)
+	a.E.GET("/catalog/categories/:id/descendants", a.CategoryDescendants)
+	a.E.GET("/catalog/categories/:id/stats", a.CategoryStats, a.Auth.PermissionMiddleware("category", "read"))
+	a.E.PUT("/catalog/categories/:id", a.UpdateCategory, a.Auth.PermissionMiddleware("category", "update")) 
+	a.E.POST("/catalog/categories/:id/move", a.MoveCategory, a.Auth.PermissionMiddleware("category", "update"))
+	a.E.POST("/catalog/categories/:id/merge", a.MergeCategory, a.Auth.PermissionMiddleware("category", "delete"))
+	a.E.DELETE("/catalog/categories/:id", a.DeleteCategory, a.Auth.PermissionMiddleware("category", "delete"))
+	a.E.GET("/categories/:id/average-price",a.GetAveragePrice, a.Auth.PermissionMiddleware("category", "read"))  
 
 	// Product routes
-	a.E.POST("/catalog/products", a.CreateProduct,auth.RoleMiddleware(a.DB, "admin"))             
+	a.E.POST("/catalog/products", a.CreateProduct, a.Auth.PermissionMiddleware("product", "create"))             
 	a.E.GET("/catalog/products", a.ViewProducts)
-	a.E.DELETE("/catalog/products/:id", a.DeleteProduct,auth.RoleMiddleware(a.DB, "admin"))               
-	a.E.PUT("/catalog/products/:id", a.UpdateProduct,auth.RoleMiddleware(a.DB, "admin"))          
+	a.E.GET("/catalog/products/facets", a.ProductFacets)
+	a.E.DELETE("/catalog/products/:id", a.DeleteProduct, a.Auth.PermissionMiddleware("product", "delete"))               
+	a.E.PUT("/catalog/products/:id", a.UpdateProduct, a.Auth.PermissionMiddleware("product", "update"))          
+
+	// Search routes
+	a.E.GET("/catalog/search", a.Search)
+	a.E.GET("/catalog/search/suggest", a.SuggestProducts)
 
 
 
@@ -101,7 +126,7 @@ func (a *App) setRouters() {
 
 func (a *App) GetStatus(c echo.Context) error {
 
-	return c.JSON(repository.CheckConnectionStatus())
+	return c.JSON(dbx.CheckConnectionStatus())
 
 }
 
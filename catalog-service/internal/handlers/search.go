@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"savannah-store/catalog-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Search godoc
+// @Summary      Search products
+// @Description  Full text search on product and category names. Misspelled words are also searched as the closest catalog words, the last word matches as a prefix and matches in the category name boost a product. Matched words are wrapped in <em> in the highlight.
+// @Tags         Catalog
+// @Produce      json
+// @Param        q         query  string  true   "Search text"
+// @Param        page      query  int     false  "Page, default 1"
+// @Param        per_page  query  int     false  "Hits per page, default 20, at most 100"
+// @Success      200  {object} models.SearchResult
+// @Failure      400  {object} map[string]string
+// @Router       /catalog/search [get]
+func (a *App) Search(c echo.Context) error {
+	return controllers.Search(c, a.SearchIndex)
+}
+
+// SuggestProducts godoc
+// @Summary      Autocomplete product names
+// @Description  Completes the typed text to product names, the last word matches as a prefix
+// @Tags         Catalog
+// @Produce      json
+// @Param        q      query  string  true   "Typed text"
+// @Param        limit  query  int     false  "Suggestions, default 10, at most 25"
+// @Success      200  {object} map[string]interface{}
+// @Failure      400  {object} map[string]string
+// @Router       /catalog/search/suggest [get]
+func (a *App) SuggestProducts(c echo.Context) error {
+	return controllers.SuggestProducts(c, a.SearchIndex)
+}
@@ -0,0 +1,64 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"savannah-store/catalog-service/internal/models"
+	"time"
+)
+
+// salesBatch is how many products go into one sales request, order-service accepts at most 1000
+const salesBatch = 1000
+
+var orderClient = &http.Client{Timeout: 10 * time.Second}
+
+// OrderServiceConfigured tells whether ORDER_SERVICE_URL is set, without it there are no sales figures
+func OrderServiceConfigured() bool {
+	return os.Getenv("ORDER_SERVICE_URL") != ""
+}
+
+// ProductSales fetches the units sold and revenue of productIDs between from and to (inclusive YYYY-MM-DD dates,
+// empty for an open side) from the order-service internal API. Products without sales are missing from the map.
+func ProductSales(ctx context.Context, productIDs []int64, from, to string) (map[int64]models.ProductSales, error) {
+	sales := map[int64]models.ProductSales{}
+	for start := 0; start < len(productIDs); start += salesBatch {
+		end := min(start+salesBatch, len(productIDs))
+		var batch []models.ProductSales
+		req := models.ProductSalesRequest{ProductIDs: productIDs[start:end], From: from, To: to}
+		if err := callOrderService(ctx, "/internal/products/sales", req, &batch); err != nil {
+			return nil, err
+		}
+		for _, s := range batch {
+			sales[s.ProductID] = s
+		}
+	}
+	return sales, nil
+}
+
+func callOrderService(ctx context.Context, path string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, os.Getenv("ORDER_SERVICE_URL")+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", os.Getenv("INTERNAL_API_TOKEN"))
+
+	resp, err := orderClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("order-service call %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("order-service call %s failed, status: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
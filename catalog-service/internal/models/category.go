@@ -1,7 +1,5 @@
 package models
 
-import "github.com/golang-jwt/jwt/v5"
-
 // CategoryRequest is used for creating/updating categories
 type CategoryRequest struct {
 	Name     string `json:"name" validate:"required"`
@@ -20,8 +18,69 @@ type CategoryResponse struct {
 	Name     string `json:"name"`
 	ParentID *int64 `json:"parent_id,omitempty"`
 }
-type JwtCustomClaims struct {
-	UserID int64  `json:"user_id"`
-	Email  string `json:"email"`
-	jwt.RegisteredClaims
+
+// CategoryNode is a category in the tree, Depth counts the levels below the node the query started from
+type CategoryNode struct {
+	ID       int64           `json:"id"`
+	Name     string          `json:"name"`
+	ParentID *int64          `json:"parent_id,omitempty"`
+	Depth    int             `json:"depth"`
+	Children []*CategoryNode `json:"children,omitempty"`
+}
+
+// CategoryMoveRequest moves a category below ParentID, to the top level when it is null
+type CategoryMoveRequest struct {
+	ParentID *int64 `json:"parent_id"`
+	// Subtree moves the children along, the default; false leaves them with the old parent
+	Subtree *bool `json:"subtree"`
+}
+
+// CategoryMergeRequest merges a category into the category Into
+type CategoryMergeRequest struct {
+	Into int64 `json:"into" validate:"required"`
+}
+
+// CategoryStats describes the products below a category, Children break the figures down per direct subcategory.
+// Products filed on the category itself only count in its own figures.
+type CategoryStats struct {
+	SubtreeStats
+	From     *string        `json:"from,omitempty"`
+	To       *string        `json:"to,omitempty"`
+	Children []SubtreeStats `json:"children"`
+	// SalesUnavailable is set when order-service is configured but could not be reached
+	SalesUnavailable bool `json:"sales_unavailable,omitempty"`
+}
+
+// SubtreeStats are the figures of a category and every category below it. Prices are zero without products,
+// Sales is left out when order-service is not configured or can not be reached.
+type SubtreeStats struct {
+	CategoryID   int64              `json:"category_id"`
+	CategoryName string             `json:"category_name"`
+	ProductCount int                `json:"product_count"`
+	MinPrice     float64            `json:"min_price"`
+	MaxPrice     float64            `json:"max_price"`
+	AveragePrice float64            `json:"average_price"`
+	MedianPrice  float64            `json:"median_price"`
+	Percentiles  map[string]float64 `json:"percentiles"`
+	Sales        *CategorySales     `json:"sales,omitempty"`
+}
+
+// CategorySales sums the order-service sales of the products below a category, cancelled orders left out
+type CategorySales struct {
+	UnitsSold int64   `json:"units_sold"`
+	Revenue   float64 `json:"revenue"`
+}
+
+// ProductSalesRequest asks the order-service internal API for the sales of ProductIDs between From and To
+type ProductSalesRequest struct {
+	ProductIDs []int64 `json:"product_ids"`
+	From       string  `json:"from,omitempty"`
+	To         string  `json:"to,omitempty"`
+}
+
+// ProductSales are the units sold and revenue of a product as reported by order-service
+type ProductSales struct {
+	ProductID int64   `json:"product_id"`
+	UnitsSold int64   `json:"units_sold"`
+	Revenue   float64 `json:"revenue"`
 }
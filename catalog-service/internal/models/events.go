@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// CategoryMovedEvent is published as category.moved when a category gets a new parent. A nil parent is
+// the top level. With Subtree false only the category moved and its children took its old place.
+type CategoryMovedEvent struct {
+	CategoryID         int64     `json:"category_id"`
+	OldParentID        *int64    `json:"old_parent_id"`
+	NewParentID        *int64    `json:"new_parent_id"`
+	Subtree            bool      `json:"subtree"`
+	ChildrenReparented int64     `json:"children_reparented"`
+	MovedAt            time.Time `json:"moved_at"`
+}
+
+// CategoriesMergedEvent is published as category.merged when a category is merged into another one and deleted
+type CategoriesMergedEvent struct {
+	SourceID      int64     `json:"source_id"`
+	TargetID      int64     `json:"target_id"`
+	ProductsMoved int64     `json:"products_moved"`
+	ChildrenMoved int64     `json:"children_moved"`
+	MergedAt      time.Time `json:"merged_at"`
+}
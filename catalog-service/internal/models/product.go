@@ -2,22 +2,99 @@ package models
 
 // ProductRequest is used for creating a product
 type ProductRequest struct {
-	Name       string  `json:"name" validate:"required"`
-	Price      float64 `json:"price" validate:"required"`
-	CategoryID int64   `json:"category_id" validate:"required"`
+	Name       string            `json:"name" validate:"required"`
+	Price      float64           `json:"price" validate:"required"`
+	CategoryID int64             `json:"category_id" validate:"required"`
+	Attributes map[string]string `json:"attributes"` // e.g. {"brand": "Samsung", "color": "black"}
 }
 
 // ProductUpdateRequest allows partial updates
 type ProductUpdateRequest struct {
-	Name       string  `json:"name"`
-	Price      float64 `json:"price"`
-	CategoryID int64   `json:"category_id"`
+	Name       string            `json:"name"`
+	Price      float64           `json:"price"`
+	CategoryID int64             `json:"category_id"`
+	Attributes map[string]string `json:"attributes"` // replaces every attribute when set
 }
 
 // ProductResponse is returned to the client
 type ProductResponse struct {
-	ID         int64   `json:"id"`
-	Name       string  `json:"name"`
-	Price      float64 `json:"price"`
-	CategoryID int64   `json:"category_id"`
+	ID         int64             `json:"id"`
+	Name       string            `json:"name"`
+	Price      float64           `json:"price"`
+	CategoryID int64             `json:"category_id"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// ProductFilter narrows and orders a product listing
+type ProductFilter struct {
+	// CategoryID matches the category and every category below it
+	CategoryID int64
+	MinPrice   *float64
+	MaxPrice   *float64
+	// NamePrefix matches product names starting with it, case insensitive
+	NamePrefix string
+	// Attributes matches products holding one of the values of every attribute name
+	Attributes map[string][]string
+	// Sort is one of price, -price, name, -name or newest
+	Sort    string
+	Page    int
+	PerPage int
+	// Cursor continues a listing after the last product of the previous page, Page is ignored when set
+	Cursor *ProductCursor
+}
+
+// ProductCursor is the position of the last product of a page in the sort order
+type ProductCursor struct {
+	Sort  string  `json:"s"`
+	ID    int64   `json:"id"`
+	Price float64 `json:"p,omitempty"`
+	Name  string  `json:"n,omitempty"`
+}
+
+// ProductList is a page of products
+type ProductList struct {
+	Products   []ProductResponse `json:"products"`
+	Page       int               `json:"page,omitempty"`
+	PerPage    int               `json:"per_page"`
+	Total      int               `json:"total"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// ProductFacets counts the products matching a filter per category, price band and attribute value.
+// Every facet leaves out its own filter, so the alternatives to the current choice keep their counts.
+type ProductFacets struct {
+	Total      int                              `json:"total"`
+	Categories []*CategoryFacet                 `json:"categories"`
+	Price      PriceFacet                       `json:"price"`
+	Attributes map[string][]AttributeValueCount `json:"attributes"`
+}
+
+// CategoryFacet counts the products of a category and every category below it
+type CategoryFacet struct {
+	ID       int64            `json:"id"`
+	Name     string           `json:"name"`
+	ParentID *int64           `json:"parent_id,omitempty"`
+	Count    int              `json:"count"`
+	Children []*CategoryFacet `json:"children,omitempty"`
+}
+
+// PriceFacet is a histogram of the prices in bands of Interval
+type PriceFacet struct {
+	Min      float64       `json:"min"`
+	Max      float64       `json:"max"`
+	Interval float64       `json:"interval"`
+	Buckets  []PriceBucket `json:"buckets"`
+}
+
+// PriceBucket counts the products priced from From up to, but not including, To
+type PriceBucket struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+// AttributeValueCount counts the products holding an attribute value
+type AttributeValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
 }
@@ -0,0 +1,27 @@
+package models
+
+// SearchQuery is a product search
+type SearchQuery struct {
+	Text    string
+	Page    int
+	PerPage int
+}
+
+// SearchHit is a product matching a search, Highlight is its HTML escaped name with the matched terms in <em>
+type SearchHit struct {
+	ProductResponse
+	CategoryName string  `json:"category_name,omitempty"`
+	Score        float64 `json:"score"`
+	Highlight    string  `json:"highlight"`
+}
+
+// SearchResult is a page of search hits, best first. Corrections lists the catalog words a misspelled
+// term was also searched as.
+type SearchResult struct {
+	Query       string              `json:"query"`
+	Hits        []SearchHit         `json:"hits"`
+	Page        int                 `json:"page"`
+	PerPage     int                 `json:"per_page"`
+	Total       int                 `json:"total"`
+	Corrections map[string][]string `json:"corrections,omitempty"`
+}
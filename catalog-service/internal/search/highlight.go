@@ -0,0 +1,56 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Highlight HTML escapes text and wraps every word equal to one of words, or starting with one of
+// prefixes, in <em></em>
+func Highlight(text string, words, prefixes []string) string {
+	match := func(word string) bool {
+		word = strings.ToLower(word)
+		for _, w := range words {
+			if word == w {
+				return true
+			}
+		}
+		for _, p := range prefixes {
+			if strings.HasPrefix(word, p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var (
+		b     strings.Builder
+		start = -1
+	)
+	flush := func(end int) {
+		word := text[start:end]
+		if match(word) {
+			b.WriteString("<em>" + html.EscapeString(word) + "</em>")
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+		start = -1
+	}
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			flush(i)
+		}
+		if !inWord {
+			b.WriteString(html.EscapeString(string(r)))
+		}
+	}
+	if start >= 0 {
+		flush(len(text))
+	}
+	return b.String()
+}
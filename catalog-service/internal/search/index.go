@@ -0,0 +1,26 @@
+// Package search finds products by their name. Index hides the engine, MySQLIndex runs on the
+// FULLTEXT indexes of the catalog database and a dedicated engine can replace it behind the same interface.
+package search
+
+import (
+	"context"
+	"savannah-store/catalog-service/internal/models"
+	"strings"
+	"unicode"
+)
+
+// Index is a product search engine
+type Index interface {
+	// Search returns the page of products matching the query, most relevant first. Misspelled terms are
+	// also searched as the closest catalog words and the last term matches as a prefix.
+	Search(ctx context.Context, q models.SearchQuery) (*models.SearchResult, error)
+	// Suggest completes prefix to at most limit product names, for search as you type
+	Suggest(ctx context.Context, prefix string, limit int) ([]string, error)
+}
+
+// Terms splits text into lower case words, anything but letters and digits separates them
+func Terms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,147 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"savannah-store/catalog-service/internal/models"
+	"strings"
+)
+
+// categoryBoost weighs a match on the category name of a product against a match on its own name, so
+// "samsung phone" ranks the Samsung products filed under Phones first
+const categoryBoost = 0.5
+
+// minTermLength is the default innodb_ft_min_token_size, shorter words are left out of the FULLTEXT index
+const minTermLength = 3
+
+// MySQLIndex searches the FULLTEXT indexes on products.name and categories.name in boolean mode
+type MySQLIndex struct {
+	db    *sql.DB
+	vocab *vocabulary
+}
+
+// NewMySQLIndex returns an Index on the catalog database
+func NewMySQLIndex(db *sql.DB) *MySQLIndex {
+	return &MySQLIndex{db: db, vocab: &vocabulary{db: db}}
+}
+
+// Search implements Index. Every term also matches its corrections and the last one matches as a prefix;
+// products rank by how well their name matches plus categoryBoost times how well their category's name does.
+func (m *MySQLIndex) Search(ctx context.Context, q models.SearchQuery) (*models.SearchResult, error) {
+	result := &models.SearchResult{Query: q.Text, Hits: []models.SearchHit{}, Page: q.Page, PerPage: q.PerPage}
+	terms := Terms(q.Text)
+	if len(terms) == 0 {
+		return result, nil
+	}
+
+	var groups, words, prefixes []string
+	for i, term := range terms {
+		alternatives := []string{term}
+		corrections, err := m.vocab.corrections(ctx, term)
+		if err != nil {
+			return nil, err
+		}
+		if len(corrections) > 0 {
+			if result.Corrections == nil {
+				result.Corrections = map[string][]string{}
+			}
+			result.Corrections[term] = corrections
+			alternatives = append(alternatives, corrections...)
+		}
+
+		last := i == len(terms)-1
+		group := make([]string, len(alternatives))
+		for j, alt := range alternatives {
+			group[j] = alt
+			if last {
+				group[j] += "*"
+			}
+		}
+		if last {
+			prefixes = append(prefixes, alternatives...)
+		} else {
+			words = append(words, alternatives...)
+		}
+		if len(group) == 1 {
+			groups = append(groups, group[0])
+		} else {
+			groups = append(groups, "("+strings.Join(group, " ")+")")
+		}
+	}
+	expr := strings.Join(groups, " ")
+
+	const (
+		from  = ` FROM products p LEFT JOIN categories c ON c.id = p.category_id`
+		where = ` WHERE MATCH(p.name) AGAINST(? IN BOOLEAN MODE) OR MATCH(c.name) AGAINST(? IN BOOLEAN MODE)`
+	)
+	if err := m.db.QueryRowContext(ctx, `SELECT COUNT(*)`+from+where, expr, expr).Scan(&result.Total); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT p.id, p.name, p.price, COALESCE(p.category_id, 0), COALESCE(c.name, ''),
+			MATCH(p.name) AGAINST(? IN BOOLEAN MODE) + ? * COALESCE(MATCH(c.name) AGAINST(? IN BOOLEAN MODE), 0) AS score`+
+		from+where+`
+		ORDER BY score DESC, p.id DESC
+		LIMIT ? OFFSET ?`,
+		expr, categoryBoost, expr, expr, expr, q.PerPage, (q.Page-1)*q.PerPage)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hit models.SearchHit
+		if err := rows.Scan(&hit.ID, &hit.Name, &hit.Price, &hit.CategoryID, &hit.CategoryName, &hit.Score); err != nil {
+			return nil, err
+		}
+		hit.Highlight = Highlight(hit.Name, words, prefixes)
+		result.Hits = append(result.Hits, hit)
+	}
+	return result, rows.Err()
+}
+
+// Suggest implements Index, names holding every term of prefix, the last one as a prefix, best match first
+func (m *MySQLIndex) Suggest(ctx context.Context, prefix string, limit int) ([]string, error) {
+	terms := Terms(prefix)
+	if len(terms) == 0 {
+		return []string{}, nil
+	}
+	// words shorter than the FULLTEXT minimum are not indexed and would never match as required terms,
+	// only the last one is kept as a prefix
+	var required []string
+	for i, term := range terms {
+		switch {
+		case i == len(terms)-1:
+			required = append(required, "+"+term+"*")
+		case len([]rune(term)) >= minTermLength:
+			required = append(required, "+"+term)
+		}
+	}
+	expr := strings.Join(required, " ")
+
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT name, MAX(MATCH(name) AGAINST(? IN BOOLEAN MODE)) AS score
+		FROM products
+		WHERE MATCH(name) AGAINST(? IN BOOLEAN MODE)
+		GROUP BY name
+		ORDER BY score DESC, name
+		LIMIT ?`, expr, expr, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var (
+			name  string
+			score float64
+		)
+		if err := rows.Scan(&name, &score); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
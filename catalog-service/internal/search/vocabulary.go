@@ -0,0 +1,125 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+)
+
+// vocabularyTTL is how long the words of the product names are kept before they are read again
+const vocabularyTTL = 5 * time.Minute
+
+// maxCorrections caps the catalog words a misspelled term is also searched as
+const maxCorrections = 3
+
+// vocabulary holds every word of the product names, misspelled terms are corrected against it
+type vocabulary struct {
+	db *sql.DB
+
+	mu       sync.Mutex
+	words    map[string]bool
+	loadedAt time.Time
+}
+
+// corrections returns the catalog words closest to term, none when term is itself a catalog word or too short
+// to guess at. Terms of up to four letters may be one edit off, longer ones two.
+func (v *vocabulary) corrections(ctx context.Context, term string) ([]string, error) {
+	words, err := v.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if words[term] || len([]rune(term)) < 4 {
+		return nil, nil
+	}
+	maxEdits := 1
+	if len([]rune(term)) > 4 {
+		maxEdits = 2
+	}
+
+	type candidate struct {
+		word  string
+		edits int
+	}
+	var candidates []candidate
+	for w := range words {
+		if d := editDistance(term, w, maxEdits); d <= maxEdits {
+			candidates = append(candidates, candidate{w, d})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].edits != candidates[j].edits {
+			return candidates[i].edits < candidates[j].edits
+		}
+		return candidates[i].word < candidates[j].word
+	})
+
+	var out []string
+	for i := 0; i < len(candidates) && i < maxCorrections; i++ {
+		out = append(out, candidates[i].word)
+	}
+	return out, nil
+}
+
+func (v *vocabulary) load(ctx context.Context) (map[string]bool, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.words != nil && time.Since(v.loadedAt) < vocabularyTTL {
+		return v.words, nil
+	}
+
+	rows, err := v.db.QueryContext(ctx, `SELECT name FROM products`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	words := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		for _, w := range Terms(name) {
+			words[w] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	v.words, v.loadedAt = words, time.Now()
+	return words, nil
+}
+
+// editDistance is the Levenshtein distance of a and b, any distance above max is returned as max+1
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
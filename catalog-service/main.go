@@ -7,7 +7,7 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/joho/godotenv"
 
-	"savannah-store/catalog-service/internal/repository"
+	"savannah-store/pkg/dbx"
 
 	"fmt"
 	app "savannah-store/catalog-service/internal/handlers"
@@ -31,7 +31,7 @@ func main() {
 	}
 
 	// setup database
-	dbInstance := repository.DbInstance(os.Getenv("CATALOG_DB_NAME"))
+	dbInstance := dbx.Open(os.Getenv("CATALOG_DB_NAME"))
 
 	driver, err := mysql.WithInstance(dbInstance, &mysql.Config{})
 	if err != nil {
@@ -0,0 +1,2 @@
+CREATE INDEX idx_products_price ON products (price, id);
+CREATE INDEX idx_products_name ON products (name, id);
@@ -0,0 +1,2 @@
+ALTER TABLE products ADD FULLTEXT INDEX ft_products_name (name);
+ALTER TABLE categories ADD FULLTEXT INDEX ft_categories_name (name);
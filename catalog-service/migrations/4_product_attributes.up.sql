@@ -0,0 +1,8 @@
+CREATE TABLE product_attributes (
+  product_id BIGINT NOT NULL,
+  name VARCHAR(64) NOT NULL,
+  value VARCHAR(255) NOT NULL,
+  PRIMARY KEY (product_id, name),
+  INDEX idx_product_attributes_value (name, value, product_id),
+  FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
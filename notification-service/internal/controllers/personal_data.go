@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"savannah-store/notification-service/internal/models"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ExportUserData returns every notification stored for the user :id
+func ExportUserData(c echo.Context, db *sql.DB) error {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+
+	rows, err := db.QueryContext(c.Request().Context(), `
+		SELECT id, type, recipient, COALESCE(subject, ''), message, COALESCE(status, ''), created
+		FROM notification
+		WHERE user_id = ?
+		ORDER BY id`, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer rows.Close()
+
+	data := models.UserData{Notifications: []models.NotificationRecord{}}
+	for rows.Next() {
+		var n models.NotificationRecord
+		if err := rows.Scan(&n.ID, &n.Type, &n.Recipient, &n.Subject, &n.Message, &n.Status, &n.Created); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		data.Notifications = append(data.Notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, data)
+}
+
+// EraseUserData blanks the recipient and content of the user's notifications and detaches them from the
+// user, the rows stay so delivery statistics add up. Erasing twice is harmless.
+func EraseUserData(c echo.Context, db *sql.DB) error {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || userID <= 0 {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+
+	res, err := db.ExecContext(c.Request().Context(), `
+		UPDATE notification
+		SET user_id = 0, recipient = '[erased]', subject = NULL, message = '[erased]'
+		WHERE user_id = ?`, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	var erasure models.UserDataErasure
+	erasure.NotificationsAnonymised, _ = res.RowsAffected()
+
+	log.Printf("erased notification data of user %d: %d notifications anonymised", userID, erasure.NotificationsAnonymised)
+	return c.JSON(http.StatusOK, erasure)
+}
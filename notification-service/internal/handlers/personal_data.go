@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"savannah-store/notification-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ExportUserData returns the notifications of a user for the auth-service data export
+func (a *App) ExportUserData(c echo.Context) error {
+	return controllers.ExportUserData(c, a.DB)
+}
+
+// EraseUserData anonymises the notifications of a user for the auth-service erasure job
+func (a *App) EraseUserData(c echo.Context) error {
+	return controllers.EraseUserData(c, a.DB)
+}
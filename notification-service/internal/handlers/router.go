@@ -3,9 +3,12 @@ package handlers
 import (
 	"database/sql"
 	"fmt"
-	"savannah-store/notification-service/internal/repository"
 	"savannah-store/notification-service/internal/queue"
 	"savannah-store/notification-service/internal/logger"
+	"savannah-store/pkg/authn"
+	"savannah-store/pkg/dbx"
+	"savannah-store/pkg/mq"
+	"savannah-store/pkg/redisx"
 	"github.com/go-redis/redis"
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo-contrib/session"
@@ -32,12 +35,12 @@ type App struct {
 // Initialize initializes the app with predefined configuration
 func (a *App) Initialize() {
 
-	a.RedisConnection = repository.RedisClient()
-	a.RabbitMQConn = repository.GetRabbitMQConnection()
+	a.RedisConnection = redisx.NewClient()
+	a.RabbitMQConn = mq.Dial()
 
 	dbName := os.Getenv("NOTIFICATION_DB_NAME")
 
-	dbO := repository.DbInstance(dbName)
+	dbO := dbx.Open(dbName)
 	a.DB = dbO
 	//set up queues
 	q := queue.Queue{
@@ -81,6 +84,10 @@ func (a *App) setRouters() {
 	a.E.Use(middleware.CORSWithConfig(corsConfig))
 
 	         
+	// service to service, personal data export and erasure driven by auth-service
+	a.E.GET("/internal/users/:id/data", a.ExportUserData, authn.InternalMiddleware())
+	a.E.DELETE("/internal/users/:id/data", a.EraseUserData, authn.InternalMiddleware())
+
 	//status
 	a.E.POST("/", a.GetStatus)
 	a.E.GET("/", a.GetStatus)
@@ -88,7 +95,7 @@ func (a *App) setRouters() {
 
 func (a *App) GetStatus(c echo.Context) error {
 
-	return c.JSON(repository.CheckConnectionStatus())
+	return c.JSON(dbx.CheckConnectionStatus())
 
 }
 
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// NotificationRecord is a notification stored for a user
+type NotificationRecord struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	Recipient string    `json:"recipient"`
+	Subject   string    `json:"subject,omitempty"`
+	Message   string    `json:"message"`
+	Status    string    `json:"status"`
+	Created   time.Time `json:"created"`
+}
+
+// UserData is everything notification-service holds about a user, for data subject exports
+type UserData struct {
+	Notifications []NotificationRecord `json:"notifications"`
+}
+
+// UserDataErasure reports what erasing a user changed
+type UserDataErasure struct {
+	NotificationsAnonymised int64 `json:"notifications_anonymised"`
+}
@@ -7,7 +7,7 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/joho/godotenv"
 
-	"savannah-store/notification-service/internal/repository"
+	"savannah-store/pkg/dbx"
 
 	"fmt"
 	app "savannah-store/notification-service/internal/handlers"
@@ -28,7 +28,7 @@ func main() {
 	}
 
 	// setup database
-	dbInstance := repository.DbInstance(os.Getenv("NOTIFICATION_DB_NAME"))
+	dbInstance := dbx.Open(os.Getenv("NOTIFICATION_DB_NAME"))
 
 	driver, err := mysql.WithInstance(dbInstance, &mysql.Config{})
 	if err != nil {
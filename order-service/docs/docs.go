@@ -1,4 +1,5 @@
-// Package docs Code generated by swaggo/swag. DO NOT EDIT
+// Code generated by swaggo/swag. DO NOT EDIT.
+
 package docs
 
 import "github.com/swaggo/swag"
@@ -223,6 +224,129 @@ const docTemplate = `{
                 }
             }
         },
+        "/internal/products/sales": {
+            "post": {
+                "description": "Service to service endpoint used by the catalog statistics: units sold and revenue of each requested product between the optional from and to dates, cancelled orders left out",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Internal"
+                ],
+                "summary": "Sales per product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Shared internal API token",
+                        "name": "X-Internal-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Products, at most 1000, and date window",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ProductSalesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ProductSales"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/internal/users/{id}/data": {
+            "get": {
+                "description": "Service to service endpoint used by the auth-service data export: every order with its items, and the cart",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Internal"
+                ],
+                "summary": "Export the order data of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Shared internal API token",
+                        "name": "X-Internal-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserData"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Service to service endpoint used by the auth-service erasure job: orders are detached from the user but keep their items and totals, the cart is deleted",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Internal"
+                ],
+                "summary": "Erase the order data of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Shared internal API token",
+                        "name": "X-Internal-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserDataErasure"
+                        }
+                    }
+                }
+            }
+        },
         "/orders": {
             "get": {
                 "description": "Retrieves all orders for the user. Admins can view all orders or specify a user_id query to view orders of a specific user.",
@@ -374,6 +498,57 @@ const docTemplate = `{
                 }
             }
         },
+        "models.CartItem": {
+            "type": "object",
+            "required": [
+                "product_id",
+                "quantity",
+                "user_id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Order": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CartItem"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "number"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
         "models.PlaceOrderRequest": {
             "type": "object",
             "properties": {
@@ -385,6 +560,37 @@ const docTemplate = `{
                 }
             }
         },
+        "models.ProductSales": {
+            "type": "object",
+            "properties": {
+                "product_id": {
+                    "type": "integer"
+                },
+                "revenue": {
+                    "type": "number"
+                },
+                "units_sold": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ProductSalesRequest": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "product_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
         "models.UpdateCartRequest": {
             "type": "object",
             "properties": {
@@ -398,6 +604,34 @@ const docTemplate = `{
                     "type": "integer"
                 }
             }
+        },
+        "models.UserData": {
+            "type": "object",
+            "properties": {
+                "cart": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CartItem"
+                    }
+                },
+                "orders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Order"
+                    }
+                }
+            }
+        },
+        "models.UserDataErasure": {
+            "type": "object",
+            "properties": {
+                "cart_items_deleted": {
+                    "type": "integer"
+                },
+                "orders_anonymised": {
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
@@ -412,8 +646,6 @@ var SwaggerInfo = &swag.Spec{
 	Description:      "This is the API for managing carts and orders in Savannah Store.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
-	// LeftDelim:        "{{",
-	// RightDelim:       "}}",
 }
 
 func init() {
@@ -11,6 +11,8 @@ import (
 
 	"savannah-store/order-service/internal/library"
 	"savannah-store/order-service/internal/models"
+	"savannah-store/pkg/authn"
+	"savannah-store/pkg/redisx"
 
 	"time"
 
@@ -36,7 +38,7 @@ func AddToCart(c echo.Context, db *sql.DB, redisConn *redis.Client, req *models.
 	key := fmt.Sprintf("cart:%v", req.UserID)
 
 	// Get existing cart
-	_, cartData := library.GetAllKeys(redisConn, key+"*")
+	cartData, _ := redisx.GetAll(redisConn, key+"*")
 
 	// Convert cartData (map) -> slice
 	var cart []models.CartItem
@@ -63,7 +65,7 @@ func AddToCart(c echo.Context, db *sql.DB, redisConn *redis.Client, req *models.
 	// Save back to Redis
 	for _, item := range cart {
 		val, _ := json.Marshal(item)
-		library.SetRedisKey(redisConn, fmt.Sprintf("%s:%d", key, item.ProductID), string(val))
+		redisx.Set(redisConn, fmt.Sprintf("%s:%d", key, item.ProductID), string(val))
 	}
 
 	return c.JSON(http.StatusCreated, echo.Map{"message": "item added to cart"})
@@ -76,14 +78,14 @@ func ViewCart(c echo.Context, db *sql.DB, redisConn *redis.Client, userID int64,
 
 	if role == "admin" {
 		// Admin: fetch all carts
-		err, allCarts := library.GetAllKeys(redisConn, "cart:*")
+		allCarts, err := redisx.GetAll(redisConn, "cart:*")
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to fetch carts"})
 		}
 		results = allCarts
 	} else {
 		// Normal user: fetch only their cart
-		err, userCart := library.GetAllKeys(redisConn, fmt.Sprintf("cart:%d*", userID))
+		userCart, err := redisx.GetAll(redisConn, fmt.Sprintf("cart:%d*", userID))
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to fetch cart"})
 		}
@@ -104,8 +106,8 @@ func ViewCart(c echo.Context, db *sql.DB, redisConn *redis.Client, userID int64,
 
 // Update cart item
 func UpdateCart(c echo.Context, db *sql.DB, redisConn *redis.Client) error {
-	userID := c.Get("user_id").(int64)
-	role := c.Get("role").(string)
+	userID := authn.UserID(c)
+	role := authn.Role(c)
 	productID := c.Param("id") // /cart/:id
 
 	req := new(models.UpdateCartRequest)
@@ -121,7 +123,7 @@ func UpdateCart(c echo.Context, db *sql.DB, redisConn *redis.Client) error {
 	key := fmt.Sprintf("cart:%v:%v", userID, productID)
 
 	// Fetch existing cart item
-	existingData, err := library.GetRedisKey(redisConn, key)
+	existingData, err := redisx.Get(redisConn, key)
 	var cartItem models.CartItem
 	if err == nil {
 		_ = json.Unmarshal([]byte(existingData), &cartItem)
@@ -149,7 +151,7 @@ func UpdateCart(c echo.Context, db *sql.DB, redisConn *redis.Client) error {
 
 	// Save back to Redis
 	val, _ := json.Marshal(cartItem)
-	if err := library.SetRedisKey(redisConn, key, string(val)); err != nil {
+	if err := redisx.Set(redisConn, key, string(val)); err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
 	}
 
@@ -158,8 +160,8 @@ func UpdateCart(c echo.Context, db *sql.DB, redisConn *redis.Client) error {
 
 // Delete cart item
 func DeleteCart(c echo.Context, db *sql.DB, redisConn *redis.Client) error {
-	userID := c.Get("user_id").(int64)
-	role := c.Get("role").(string)
+	userID := authn.UserID(c)
+	role := authn.Role(c)
 
 	productID := c.Param("id") // /cart/:id
 
@@ -170,7 +172,7 @@ func DeleteCart(c echo.Context, db *sql.DB, redisConn *redis.Client) error {
 	}
 
 	key := fmt.Sprintf("cart:%v:%v", userID, productID)
-	if err := library.DeleteRedisKey(redisConn, key); err != nil {
+	if err := redisx.Delete(redisConn, key); err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
 	}
 
@@ -179,8 +181,8 @@ func DeleteCart(c echo.Context, db *sql.DB, redisConn *redis.Client) error {
 
 // Place Order
 func PlaceOrder(c echo.Context, db *sql.DB, redisConn *redis.Client, mq *amqp.Connection) error {
-	userID := c.Get("user_id").(int64)
-	role := c.Get("role").(string)
+	userID := authn.UserID(c)
+	role := authn.Role(c)
 
 	if role == "admin" {
 		reqUserID := c.Param("user_id")
@@ -200,7 +202,7 @@ func PlaceOrder(c echo.Context, db *sql.DB, redisConn *redis.Client, mq *amqp.Co
 	var items []models.CartItem
 	var total float64
 	for _, key := range keys {
-		data, err := library.GetRedisKey(redisConn, key)
+		data, err := redisx.Get(redisConn, key)
 		if err != nil {
 			continue
 		}
@@ -231,12 +233,12 @@ func PlaceOrder(c echo.Context, db *sql.DB, redisConn *redis.Client, mq *amqp.Co
 
 	// Clear cart
 	for _, key := range keys {
-		_ = library.DeleteRedisKey(redisConn, key)
+		_ = redisx.Delete(redisConn, key)
 	}
 
 	go func() {
-		_ = SendSMS(db, mq, userID, orderID)
-		_ = SendEmailToAdmin(db, mq, orderID, total, items)
+		_ = SendSMS(redisConn, mq, userID, orderID)
+		_ = SendEmailToAdmin(redisConn, mq, orderID, total, items)
 	}()
 
 	return c.JSON(http.StatusCreated, echo.Map{"order_id": orderID, "total": total, "status": "Pending"})
@@ -244,8 +246,8 @@ func PlaceOrder(c echo.Context, db *sql.DB, redisConn *redis.Client, mq *amqp.Co
 
 // ViewOrders retrieves all orders for a specific user
 func ViewOrders(c echo.Context, db *sql.DB) error {
-	userID := c.Get("user_id").(int64)
-	role := c.Get("role").(string)
+	userID := authn.UserID(c)
+	role := authn.Role(c)
 
 	if role == "admin" {
 		reqUserID := c.Param("user_id")
@@ -254,7 +256,7 @@ func ViewOrders(c echo.Context, db *sql.DB) error {
 		}
 	}
 
-	rows, err := db.Query(`SELECT id, user_id, total_amount, status, created FROM orders WHERE user_id = ?`, userID)
+	rows, err := db.Query(`SELECT id, user_id, total_amount, status, created FROM orders WHERE user_id = ? AND anonymised_at IS NULL`, userID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
 	}
@@ -284,15 +286,16 @@ func ViewOrders(c echo.Context, db *sql.DB) error {
 
 // DeleteOrder removes an order by ID
 func DeleteOrder(c echo.Context, db *sql.DB) error {
-	userID := c.Get("user_id").(int64)
-	role := c.Get("role").(string)
+	userID := authn.UserID(c)
+	role := authn.Role(c)
 
 	orderID := c.Param("id")
 
-	// Admin can delete any order, users only their own
+	// Admin can delete any order, users only their own. Anonymised orders are kept for accounting and
+	// can not be deleted by anyone.
 	if role != "admin" {
 		var user int64
-		err := db.QueryRow(`SELECT user_id FROM orders WHERE id = ?`, orderID).Scan(&user)
+		err := db.QueryRow(`SELECT user_id FROM orders WHERE id = ? AND anonymised_at IS NULL`, orderID).Scan(&user)
 		if err != nil {
 			return c.JSON(http.StatusNotFound, echo.Map{"error": "order not found"})
 		}
@@ -301,22 +304,25 @@ func DeleteOrder(c echo.Context, db *sql.DB) error {
 		}
 	}
 
-	_, err := db.Exec(`DELETE FROM orders WHERE id = ?`, orderID)
+	res, err := db.Exec(`DELETE FROM orders WHERE id = ? AND anonymised_at IS NULL`, orderID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "order not found"})
+	}
 
 	return c.JSON(http.StatusOK, echo.Map{"message": "order deleted"})
 }
 
-func SendSMS(db *sql.DB, rabbitConn *amqp.Connection, userID, orderID int64) error {
-	// Fetch user phone from DB
-	var phone string
-	err := db.QueryRow(`SELECT phone FROM authdb.users WHERE id = ?`, userID).Scan(&phone)
+func SendSMS(redisConn *redis.Client, rabbitConn *amqp.Connection, userID, orderID int64) error {
+	// Fetch user phone from auth-service
+	user, err := library.GetUser(redisConn, userID)
 	if err != nil {
 		log.Printf("Failed to fetch user phone for userID %d: %v\n", userID, err)
 		return err
 	}
+	phone := user.Phone
 
 	if phone == "" {
 		return fmt.Errorf("user %d has no phone number", userID)
@@ -340,28 +346,17 @@ func SendSMS(db *sql.DB, rabbitConn *amqp.Connection, userID, orderID int64) err
 	return nil
 }
 
-func SendEmailToAdmin(db *sql.DB, rabbitConn *amqp.Connection, orderID int64, total float64, items []models.CartItem) error {
-	// Fetch admin emails (could be multiple)
-	rows, err := db.Query(`
-		SELECT u.email
-		FROM authdb.users u
-		JOIN authdb.roles r ON u.role_id = r.id
-		WHERE r.name = 'admin'
-	`)
+func SendEmailToAdmin(redisConn *redis.Client, rabbitConn *amqp.Connection, orderID int64, total float64, items []models.CartItem) error {
+	// Fetch admin emails (could be multiple) from auth-service
+	admins, err := library.GetUsersByRole(redisConn, "admin")
 	if err != nil {
 		log.Println("Failed to fetch admin emails:", err)
 		return err
 	}
-	defer rows.Close()
 
 	var adminEmails []string
-	for rows.Next() {
-		var email string
-		if err := rows.Scan(&email); err != nil {
-			log.Println("Failed to scan admin email:", err)
-			continue
-		}
-		adminEmails = append(adminEmails, email)
+	for _, admin := range admins {
+		adminEmails = append(adminEmails, admin.Email)
 	}
 
 	if len(adminEmails) == 0 {
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"savannah-store/order-service/internal/models"
+	"savannah-store/pkg/redisx"
+	"strconv"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+)
+
+// cart items live under cart:<user_id>:<product_id>
+func userCartPattern(userID int64) string { return fmt.Sprintf("cart:%d:*", userID) }
+
+// ExportUserData returns the orders, with their items, and the cart of the user :id
+func ExportUserData(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+	ctx := c.Request().Context()
+
+	data := models.UserData{Orders: []models.Order{}, Cart: []models.CartItem{}}
+	rows, err := db.QueryContext(ctx, `SELECT id, user_id, total_amount, status, created FROM orders WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer rows.Close()
+	for rows.Next() {
+		o := models.Order{Items: []models.CartItem{}}
+		if err := rows.Scan(&o.ID, &o.UserID, &o.Total, &o.Status, &o.CreatedAt); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		data.Orders = append(data.Orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	for i := range data.Orders {
+		items, err := db.QueryContext(ctx, `SELECT id, product_id, quantity, price FROM order_items WHERE order_id = ? ORDER BY id`, data.Orders[i].ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		for items.Next() {
+			item := models.CartItem{UserID: userID}
+			if err := items.Scan(&item.ID, &item.ProductID, &item.Quantity, &item.Price); err != nil {
+				items.Close()
+				return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+			}
+			data.Orders[i].Items = append(data.Orders[i].Items, item)
+		}
+		items.Close()
+	}
+
+	cart, err := redisx.GetAll(rdb, userCartPattern(userID))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to fetch cart"})
+	}
+	for _, v := range cart {
+		var item models.CartItem
+		if json.Unmarshal([]byte(v), &item) == nil {
+			data.Cart = append(data.Cart, item)
+		}
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+// EraseUserData anonymises the orders of the user :id and drops the cart and cached auth lookups. Orders keep
+// their items and totals for accounting but belong to no user (user_id NULL), so no caller can list or delete
+// them. Erasing twice is harmless.
+func EraseUserData(c echo.Context, db *sql.DB, rdb *redis.Client) error {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || userID <= 0 {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+
+	res, err := db.ExecContext(c.Request().Context(), `UPDATE orders SET user_id = NULL, anonymised_at = NOW() WHERE user_id = ?`, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	var erasure models.UserDataErasure
+	erasure.OrdersAnonymised, _ = res.RowsAffected()
+
+	keys, err := rdb.Keys(userCartPattern(userID)).Result()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to fetch cart"})
+	}
+	erasure.CartItemsDeleted = len(keys)
+
+	// cached auth-service lookups hold the profile, the role lists may hold it too
+	cached, _ := rdb.Keys("authcache:role:*").Result()
+	keys = append(append(keys, fmt.Sprintf("authcache:user:%d", userID)), cached...)
+	if err := rdb.Del(keys...).Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to delete cart"})
+	}
+
+	log.Printf("erased order data of user %d: %d orders anonymised, %d cart items deleted", userID, erasure.OrdersAnonymised, erasure.CartItemsDeleted)
+	return c.JSON(http.StatusOK, erasure)
+}
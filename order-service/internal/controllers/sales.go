@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"savannah-store/order-service/internal/models"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxSalesProducts caps the products of one sales request, callers split longer lists
+const maxSalesProducts = 1000
+
+// ProductSales sums the units sold and revenue of the requested products, cancelled orders left out. Orders of
+// erased users still count. Products without sales are left out of the answer.
+func ProductSales(c echo.Context, db *sql.DB) error {
+	req := new(models.ProductSalesRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+	if len(req.ProductIDs) > maxSalesProducts {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "too many product_ids"})
+	}
+	sales := []models.ProductSales{}
+	if len(req.ProductIDs) == 0 {
+		return c.JSON(http.StatusOK, sales)
+	}
+
+	args := make([]interface{}, 0, len(req.ProductIDs)+2)
+	for _, id := range req.ProductIDs {
+		args = append(args, id)
+	}
+	cond := ` WHERE oi.product_id IN (?` + strings.Repeat(", ?", len(req.ProductIDs)-1) + `) AND LOWER(o.status) <> 'cancelled'`
+	if req.From != "" {
+		from, err := time.Parse(time.DateOnly, req.From)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid from, expected YYYY-MM-DD"})
+		}
+		cond += ` AND o.created >= ?`
+		args = append(args, from)
+	}
+	if req.To != "" {
+		to, err := time.Parse(time.DateOnly, req.To)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid to, expected YYYY-MM-DD"})
+		}
+		cond += ` AND o.created < ?`
+		args = append(args, to.AddDate(0, 0, 1))
+	}
+
+	rows, err := db.QueryContext(c.Request().Context(), `
+		SELECT oi.product_id, SUM(oi.quantity), SUM(oi.quantity * oi.price)
+		FROM order_items oi
+		INNER JOIN orders o ON o.id = oi.order_id`+cond+`
+		GROUP BY oi.product_id
+		ORDER BY oi.product_id`, args...)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.ProductSales
+		if err := rows.Scan(&s.ProductID, &s.UnitsSold, &s.Revenue); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		}
+		sales = append(sales, s)
+	}
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, sales)
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"savannah-store/order-service/internal/controllers"
 	"savannah-store/order-service/internal/models"
+	"savannah-store/pkg/authn"
 
 	"github.com/labstack/echo/v4"
 )
@@ -21,7 +22,7 @@ import (
 // @Failure      401   {object} map[string]string
 // @Router       /cart [post]
 func (a *App) AddToCart(c echo.Context) error {
-	userID := c.Get("user_id").(int64)
+	userID := authn.UserID(c)
 	req := new(models.CartItem)
 	if err := c.Bind(req); err != nil {
 		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
@@ -46,8 +47,8 @@ func (a *App) AddToCart(c echo.Context) error {
 // @Failure      500  {object} map[string]string "server error"
 // @Router       /cart [get]
 func (a *App) ViewCart(c echo.Context) error {
-	userID := c.Get("user_id").(int64)
-	role := c.Get("role").(string)
+	userID := authn.UserID(c)
+	role := authn.Role(c)
 
 	// Pass claims.UserID and role to controller
 	return controllers.ViewCart(c, a.DB, a.RedisConnection, userID, role)
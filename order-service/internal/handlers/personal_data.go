@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"savannah-store/order-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ExportUserData godoc
+// @Summary      Export the order data of a user
+// @Description  Service to service endpoint used by the auth-service data export: every order with its items, and the cart
+// @Tags         Internal
+// @Produce      json
+// @Param        X-Internal-Token header string true "Shared internal API token"
+// @Param        id  path  int  true  "User ID"
+// @Success      200  {object} models.UserData
+// @Router       /internal/users/{id}/data [get]
+func (a *App) ExportUserData(c echo.Context) error {
+	return controllers.ExportUserData(c, a.DB, a.RedisConnection)
+}
+
+// EraseUserData godoc
+// @Summary      Erase the order data of a user
+// @Description  Service to service endpoint used by the auth-service erasure job: orders are detached from the user but keep their items and totals, the cart is deleted
+// @Tags         Internal
+// @Produce      json
+// @Param        X-Internal-Token header string true "Shared internal API token"
+// @Param        id  path  int  true  "User ID"
+// @Success      200  {object} models.UserDataErasure
+// @Router       /internal/users/{id}/data [delete]
+func (a *App) EraseUserData(c echo.Context) error {
+	return controllers.EraseUserData(c, a.DB, a.RedisConnection)
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	_ "savannah-store/order-service/docs"
 	"savannah-store/order-service/internal/logger"
-	auth "savannah-store/order-service/internal/middleware"
-	"savannah-store/order-service/internal/repository"
+	"savannah-store/pkg/authn"
+	"savannah-store/pkg/dbx"
+	"savannah-store/pkg/mq"
+	"savannah-store/pkg/redisx"
 
 	"github.com/go-redis/redis"
 	"github.com/gorilla/sessions"
@@ -30,17 +32,19 @@ type App struct {
 	E               *echo.Echo
 	RedisConnection *redis.Client
 	RabbitMQConn    *amqp.Connection
+	Auth            *authn.Authenticator
 }
 
 // Initialize initializes the app with predefined configuration
 func (a *App) Initialize() {
 
-	a.RedisConnection = repository.RedisClient()
-	a.RabbitMQConn = repository.GetRabbitMQConnection()
+	a.RedisConnection = redisx.NewClient()
+	a.RabbitMQConn = mq.Dial()
+	a.Auth = authn.New(authn.Config{Redis: a.RedisConnection, Service: "order-service"})
 
 	dbName := os.Getenv("ORDER_DB_NAME")
 
-	dbO := repository.DbInstance(dbName)
+	dbO := dbx.Open(dbName)
 	a.DB = dbO
 
 	a.setRouters()
@@ -76,15 +80,22 @@ func (a *App) setRouters() {
 	a.E.Use(middleware.CORSWithConfig(corsConfig))
 
 	// Cart routes
-	a.E.POST("/cart", a.AddToCart, auth.RoleMiddleware(a.DB, "customer", "admin"))
-	a.E.GET("/cart", a.ViewCart, auth.RoleMiddleware(a.DB, "customer", "admin"))
-	a.E.PUT("/cart", a.UpdateCart, auth.RoleMiddleware(a.DB, "customer", "admin"))
-	a.E.DELETE("/cart", a.DeleteCart, auth.RoleMiddleware(a.DB, "customer", "admin"))
+	a.E.POST("/cart", a.AddToCart, a.Auth.UserPermissionMiddleware("cart", "create"))
+	a.E.GET("/cart", a.ViewCart, a.Auth.UserPermissionMiddleware("cart", "read"))
+	a.E.PUT("/cart", a.UpdateCart, a.Auth.UserPermissionMiddleware("cart", "update"))
+	a.E.DELETE("/cart", a.DeleteCart, a.Auth.UserPermissionMiddleware("cart", "delete"))
 
 	// Order routes
-	a.E.POST("/orders", a.PlaceOrder, auth.RoleMiddleware(a.DB, "customer", "admin"))
-	a.E.GET("/orders", a.ViewOrders, auth.RoleMiddleware(a.DB, "customer", "admin"))
-	a.E.DELETE("/orders", a.DeleteOrder, auth.RoleMiddleware(a.DB, "admin"))
+	a.E.POST("/orders", a.PlaceOrder, a.Auth.UserPermissionMiddleware("order", "create"))
+	a.E.GET("/orders", a.ViewOrders, a.Auth.UserPermissionMiddleware("order", "read"))
+	a.E.DELETE("/orders", a.DeleteOrder, a.Auth.UserPermissionMiddleware("order", "delete"))
+
+	// service to service, personal data export and erasure driven by auth-service
+	a.E.GET("/internal/users/:id/data", a.ExportUserData, authn.InternalMiddleware())
+	a.E.DELETE("/internal/users/:id/data", a.EraseUserData, authn.InternalMiddleware())
+
+	// service to service, sales figures for the catalog statistics
+	a.E.POST("/internal/products/sales", a.ProductSales, authn.InternalMiddleware())
 
 	//status
 	a.E.POST("/", a.GetStatus)
@@ -94,7 +105,7 @@ func (a *App) setRouters() {
 
 func (a *App) GetStatus(c echo.Context) error {
 
-	return c.JSON(repository.CheckConnectionStatus())
+	return c.JSON(dbx.CheckConnectionStatus())
 
 }
 
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"savannah-store/order-service/internal/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ProductSales godoc
+// @Summary      Sales per product
+// @Description  Service to service endpoint used by the catalog statistics: units sold and revenue of each requested product between the optional from and to dates, cancelled orders left out
+// @Tags         Internal
+// @Accept       json
+// @Produce      json
+// @Param        X-Internal-Token header string true "Shared internal API token"
+// @Param        body  body  models.ProductSalesRequest  true  "Products, at most 1000, and date window"
+// @Success      200  {array} models.ProductSales
+// @Failure      400  {object} map[string]string
+// @Router       /internal/products/sales [post]
+func (a *App) ProductSales(c echo.Context) error {
+	return controllers.ProductSales(c, a.DB)
+}
@@ -0,0 +1,77 @@
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"savannah-store/order-service/internal/models"
+	"savannah-store/pkg/redisx"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// lookups are cached in redis so placing orders does not hit auth-service every time
+const userCacheSeconds = 300
+
+var authClient = &http.Client{Timeout: 5 * time.Second}
+
+// GetUser fetches a user profile from auth-service, going through the redis cache
+func GetUser(conn *redis.Client, userID int64) (*models.UserProfile, error) {
+	var user models.UserProfile
+	key := fmt.Sprintf("authcache:user:%d", userID)
+	if err := cachedAuthLookup(conn, key, fmt.Sprintf("/internal/users/%d", userID), &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUsersByRole fetches every user holding role from auth-service, going through the redis cache
+func GetUsersByRole(conn *redis.Client, role string) ([]models.UserProfile, error) {
+	var users []models.UserProfile
+	key := fmt.Sprintf("authcache:role:%s", role)
+	if err := cachedAuthLookup(conn, key, "/internal/users?role="+url.QueryEscape(role), &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func cachedAuthLookup(conn *redis.Client, key, path string, out interface{}) error {
+	if data, err := redisx.Get(conn, key); err == nil {
+		if json.Unmarshal([]byte(data), out) == nil {
+			return nil
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, os.Getenv("AUTH_SERVICE_URL")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Internal-Token", os.Getenv("INTERNAL_API_TOKEN"))
+
+	resp, err := authClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("auth-service lookup failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("auth-service lookup %s failed, status: %s", path, resp.Status)
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return err
+	}
+
+	if err := redisx.SetWithExpiry(conn, key, string(raw), userCacheSeconds); err != nil {
+		log.Printf("failed to cache auth lookup %s: %v", key, err)
+	}
+	return nil
+}
@@ -1,7 +1,5 @@
 package models
 
-import "github.com/golang-jwt/jwt/v5"
-
 type CartItem struct {
 	ID        int64   `json:"id"`
 	UserID    int64   `json:"user_id" validate:"required"`
@@ -34,8 +32,17 @@ type PlaceOrderRequest struct {
 	PaymentMethod string `json:"payment_method"`
 }
 
-type JwtCustomClaims struct {
-	UserID int64  `json:"user_id"`
-	Email  string `json:"email"`
-	jwt.RegisteredClaims
+// ProductSalesRequest asks for the sales of ProductIDs placed between From and To, inclusive YYYY-MM-DD dates,
+// either one may be empty to leave that side open
+type ProductSalesRequest struct {
+	ProductIDs []int64 `json:"product_ids"`
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+}
+
+// ProductSales sums the order lines of a product, cancelled orders left out
+type ProductSales struct {
+	ProductID int64   `json:"product_id"`
+	UnitsSold int64   `json:"units_sold"`
+	Revenue   float64 `json:"revenue"`
 }
@@ -0,0 +1,23 @@
+package models
+
+// UserProfile is the user record returned by the auth-service internal lookup API
+type UserProfile struct {
+	ID            int64  `json:"id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Phone         string `json:"phone,omitempty"`
+	FullName      string `json:"full_name,omitempty"`
+	Role          string `json:"role"`
+}
+
+// UserData is everything order-service holds about a user, for data subject exports
+type UserData struct {
+	Orders []Order    `json:"orders"`
+	Cart   []CartItem `json:"cart"`
+}
+
+// UserDataErasure reports what erasing a user changed
+type UserDataErasure struct {
+	OrdersAnonymised int64 `json:"orders_anonymised"`
+	CartItemsDeleted int   `json:"cart_items_deleted"`
+}
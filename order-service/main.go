@@ -6,7 +6,7 @@ import (
 
 	_ "github.com/go-sql-driver/mysql"
 
-	"savannah-store/order-service/internal/repository"
+	"savannah-store/pkg/dbx"
 
 	"fmt"
 	app "savannah-store/order-service/internal/handlers"
@@ -35,7 +35,7 @@ func main() {
 	}
 
 	// setup database
-	dbInstance := repository.DbInstance(os.Getenv("ORDER_DB_NAME"))
+	dbInstance := dbx.Open(os.Getenv("ORDER_DB_NAME"))
 
 	driver, err := mysql.WithInstance(dbInstance, &mysql.Config{})
 	if err != nil {
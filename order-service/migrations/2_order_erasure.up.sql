@@ -0,0 +1,3 @@
+-- orders of erased users are kept for accounting, detached from the user (user_id NULL, as 0 is what machine clients act as)
+ALTER TABLE orders ADD COLUMN anonymised_at TIMESTAMP NULL DEFAULT NULL;
+ALTER TABLE orders MODIFY user_id BIGINT NULL;
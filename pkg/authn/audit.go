@@ -0,0 +1,72 @@
+package authn
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var auditClient = &http.Client{Timeout: 5 * time.Second}
+
+// AuditEntry is a request served with an impersonation token, recorded by auth-service
+type AuditEntry struct {
+	ImpersonationID string `json:"impersonation_id"`
+	AdminID         int64  `json:"admin_id"`
+	UserID          int64  `json:"user_id"`
+	Service         string `json:"service"`
+	Method          string `json:"method"`
+	Path            string `json:"path"`
+	Status          int    `json:"status"`
+	IP              string `json:"ip"`
+}
+
+// auditImpersonation records a request served with an impersonation token in the auth-service audit trail
+func (a *Authenticator) auditImpersonation(c echo.Context, claims *Claims, err error) {
+	if claims.Act == nil {
+		return
+	}
+	status := c.Response().Status
+	if he, ok := err.(*echo.HTTPError); ok {
+		status = he.Code
+	} else if err != nil && !c.Response().Committed {
+		status = http.StatusInternalServerError
+	}
+	go recordImpersonation(AuditEntry{
+		ImpersonationID: claims.SessionID,
+		AdminID:         claims.Act.UserID,
+		UserID:          claims.UserID,
+		Service:         a.cfg.Service,
+		Method:          c.Request().Method,
+		Path:            c.Request().URL.RequestURI(),
+		Status:          status,
+		IP:              c.RealIP(),
+	})
+}
+
+// recordImpersonation posts the entry to AUTH_SERVICE_URL, it runs in the background so auditing never slows
+// the request down; failures are logged
+func recordImpersonation(entry AuditEntry) {
+	body, _ := json.Marshal(entry)
+	req, err := http.NewRequest(http.MethodPost, os.Getenv("AUTH_SERVICE_URL")+"/internal/audit/impersonation", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("failed to audit impersonation %s: %v", entry.ImpersonationID, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", os.Getenv("INTERNAL_API_TOKEN"))
+
+	resp, err := auditClient.Do(req)
+	if err != nil {
+		log.Printf("failed to audit impersonation %s: %v", entry.ImpersonationID, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		log.Printf("failed to audit impersonation %s, status: %s", entry.ImpersonationID, resp.Status)
+	}
+}
@@ -0,0 +1,80 @@
+package authn
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// past this many entries the expired ones are dropped, and everything if that is not enough
+const maxCachedClaims = 10000
+
+// claimsCache keeps verified claims by token hash so a token's signature is checked once, not on every
+// request. Revocation is not cached, it is checked on every request.
+type claimsCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedClaims
+}
+
+type cachedClaims struct {
+	claims *Claims
+	until  time.Time
+}
+
+func newClaimsCache(ttl time.Duration) *claimsCache {
+	return &claimsCache{ttl: ttl, entries: map[string]cachedClaims{}}
+}
+
+func cacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns a copy of the cached claims, nil when the token is not cached
+func (cc *claimsCache) get(token string) *Claims {
+	if cc == nil {
+		return nil
+	}
+	key := cacheKey(token)
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	entry, ok := cc.entries[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(entry.until) {
+		delete(cc.entries, key)
+		return nil
+	}
+	claims := *entry.claims
+	return &claims
+}
+
+// put caches the claims for the cache ttl, never past the expiry of the token
+func (cc *claimsCache) put(token string, claims *Claims) {
+	if cc == nil {
+		return
+	}
+	until := time.Now().Add(cc.ttl)
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(until) {
+		until = claims.ExpiresAt.Time
+	}
+	stored := *claims
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if len(cc.entries) >= maxCachedClaims {
+		now := time.Now()
+		for k, e := range cc.entries {
+			if now.After(e.until) {
+				delete(cc.entries, k)
+			}
+		}
+		if len(cc.entries) >= maxCachedClaims {
+			cc.entries = map[string]cachedClaims{}
+		}
+	}
+	cc.entries[cacheKey(token)] = cachedClaims{claims: &stored, until: until}
+}
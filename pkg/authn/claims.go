@@ -0,0 +1,28 @@
+// Package authn verifies the access tokens issued by auth-service and hands the caller to the handlers.
+// Every service guards its routes with an Authenticator; auth-service plugs in its own signing keys.
+package authn
+
+import "github.com/golang-jwt/jwt/v5"
+
+// Claims are the claims of the access tokens issued by auth-service
+type Claims struct {
+	UserID int64    `json:"user_id"`
+	Email  string   `json:"email"`
+	RoleID int64    `json:"role_id,omitempty"`
+	Role   string   `json:"role,omitempty"`
+	Perms  []string `json:"perms,omitempty"`
+	// SessionID is the refresh token family the access token was issued for
+	SessionID string `json:"sid,omitempty"`
+	// ClientID is set instead of UserID on tokens of machine clients, Perms then holds the granted scopes
+	ClientID string `json:"client_id,omitempty"`
+	// Act names the admin behind an impersonation token (RFC 8693 actor claim), the rest of the claims are the user's
+	Act *Actor `json:"act,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Actor is the admin acting as the user of an impersonation token
+type Actor struct {
+	Sub    string `json:"sub"`
+	UserID int64  `json:"user_id"`
+	Email  string `json:"email,omitempty"`
+}
@@ -0,0 +1,55 @@
+package authn
+
+import "github.com/labstack/echo/v4"
+
+// SetContext stores the caller in the echo context. user_id is 0 for machine clients; on impersonation
+// tokens user_id is the impersonated user and impersonator_id the admin acting as them.
+func SetContext(c echo.Context, claims *Claims) {
+	c.Set("claims", claims)
+	c.Set("user_id", claims.UserID)
+	c.Set("client_id", claims.ClientID)
+	c.Set("email", claims.Email)
+	c.Set("role", claims.Role)
+	if claims.Act != nil {
+		c.Set("impersonator_id", claims.Act.UserID)
+		c.Set("impersonator_email", claims.Act.Email)
+	}
+}
+
+// ClaimsFrom returns the claims of the caller, nil on routes without authentication
+func ClaimsFrom(c echo.Context) *Claims {
+	claims, _ := c.Get("claims").(*Claims)
+	return claims
+}
+
+// UserID returns the calling user, 0 for anonymous callers and machine clients
+func UserID(c echo.Context) int64 {
+	id, _ := c.Get("user_id").(int64)
+	return id
+}
+
+// ClientID returns the calling machine client, empty for users
+func ClientID(c echo.Context) string {
+	id, _ := c.Get("client_id").(string)
+	return id
+}
+
+// Email returns the email of the calling user
+func Email(c echo.Context) string {
+	email, _ := c.Get("email").(string)
+	return email
+}
+
+// Role returns the role of the calling user
+func Role(c echo.Context) string {
+	role, _ := c.Get("role").(string)
+	return role
+}
+
+// Impersonator returns the admin acting as the calling user, nil unless the request uses an impersonation token
+func Impersonator(c echo.Context) *Actor {
+	if claims := ClaimsFrom(c); claims != nil {
+		return claims.Act
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package authn
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+)
+
+// InternalMiddleware only lets through service to service calls carrying the shared INTERNAL_API_TOKEN
+func InternalMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			expected := os.Getenv("INTERNAL_API_TOKEN")
+			given := c.Request().Header.Get("X-Internal-Token")
+			if expected == "" || subtle.ConstantTimeCompare([]byte(given), []byte(expected)) != 1 {
+				return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid internal token"})
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package authn
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultJWKSCacheTTL = 10 * time.Minute
+	// unknown kids trigger a refetch at most this often, so garbage tokens can not hammer auth-service
+	jwksMinRefetchInterval = 30 * time.Second
+)
+
+// jwk is one key of the auth-service JSON Web Key Set
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+}
+
+type verificationKey struct {
+	alg string
+	key interface{}
+}
+
+// jwksCache holds the public keys of auth-service, fetched from AUTH_JWKS_URL
+type jwksCache struct {
+	mu          sync.RWMutex
+	keys        map[string]verificationKey
+	fetched     time.Time
+	lastAttempt time.Time
+	client      *http.Client
+}
+
+var keySet = &jwksCache{client: &http.Client{Timeout: 5 * time.Second}}
+
+// signingMethods are the algorithms auth-service signs with
+var signingMethods = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+
+// keyFunc resolves the auth-service public key of a token from its kid header
+func keyFunc(t *jwt.Token) (interface{}, error) {
+	// auth-service signs other tokens (e.g. invites) with the same keys, only access tokens are accepted here
+	if typ, _ := t.Header["typ"].(string); typ != "" && typ != "JWT" {
+		return nil, fmt.Errorf("unexpected token type %s", typ)
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("token has no kid")
+	}
+
+	key, err := keySet.get(kid)
+	if err != nil {
+		return nil, err
+	}
+	if key.alg != t.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	}
+	return key.key, nil
+}
+
+func (j *jwksCache) get(kid string) (verificationKey, error) {
+	j.mu.RLock()
+	key, ok := j.keys[kid]
+	stale := time.Since(j.fetched) > jwksCacheTTL()
+	canRefetch := time.Since(j.lastAttempt) > jwksMinRefetchInterval
+	j.mu.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+
+	// a rotated key shows up as an unknown kid, refetch unless we just did
+	if stale || canRefetch {
+		if err := j.refresh(); err != nil && !ok {
+			return verificationKey{}, err
+		}
+		j.mu.RLock()
+		key, ok = j.keys[kid]
+		j.mu.RUnlock()
+	}
+
+	if !ok {
+		return verificationKey{}, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func (j *jwksCache) refresh() error {
+	j.mu.Lock()
+	j.lastAttempt = time.Now()
+	j.mu.Unlock()
+
+	resp, err := j.client.Get(os.Getenv("AUTH_JWKS_URL"))
+	if err != nil {
+		return fmt.Errorf("failed to fetch jwks: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch jwks, status: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to parse jwks: %v", err)
+	}
+
+	keys := map[string]verificationKey{}
+	for _, k := range set.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = verificationKey{alg: k.Alg, key: key}
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.fetched = time.Now()
+	j.mu.Unlock()
+	return nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+// jwksCacheTTL is how long fetched keys are used before refetching, JWKS_CACHE_TTL (seconds)
+func jwksCacheTTL() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("JWKS_CACHE_TTL"))
+	if err != nil || seconds <= 0 {
+		return defaultJWKSCacheTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,177 @@
+package authn
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultCacheTTL is how long verified claims are cached when Config.CacheTTL is zero
+const DefaultCacheTTL = time.Minute
+
+// Config configures an Authenticator
+type Config struct {
+	// Redis holds the revocation denylist written by auth-service
+	Redis *redis.Client
+	// Headers the token is read from, in order; DefaultHeaders when empty
+	Headers []string
+	// Service names the service in the impersonation audit trail
+	Service string
+	// CacheTTL is how long verified claims are cached, DefaultCacheTTL when zero, no caching when negative
+	CacheTTL time.Duration
+	// Parse verifies a token and fills claims, by default against the auth-service JWKS (AUTH_JWKS_URL).
+	// auth-service sets it to verify with its own keys.
+	Parse func(token string, claims jwt.Claims) error
+}
+
+// Authenticator checks the access tokens of incoming requests
+type Authenticator struct {
+	cfg   Config
+	cache *claimsCache
+}
+
+// New returns an Authenticator for cfg
+func New(cfg Config) *Authenticator {
+	if len(cfg.Headers) == 0 {
+		cfg.Headers = DefaultHeaders
+	}
+	if cfg.Parse == nil {
+		cfg.Parse = parseWithJWKS
+	}
+	a := &Authenticator{cfg: cfg}
+	switch {
+	case cfg.CacheTTL == 0:
+		a.cache = newClaimsCache(DefaultCacheTTL)
+	case cfg.CacheTTL > 0:
+		a.cache = newClaimsCache(cfg.CacheTTL)
+	}
+	return a
+}
+
+// RoleMiddleware lets through callers holding one of allowedRoles, "all" allows every authenticated caller
+func (a *Authenticator) RoleMiddleware(allowedRoles ...string) echo.MiddlewareFunc {
+	return a.guard(func(claims *Claims) *echo.HTTPError {
+		if !isRoleAllowed(claims.Role, allowedRoles) {
+			return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("role '%s' not authorized", claims.Role))
+		}
+		return nil
+	})
+}
+
+// PermissionMiddleware lets through callers whose token grants action on module, e.g.
+// PermissionMiddleware("product", "create"). Permissions are embedded in the token by auth-service,
+// so grants and revocations apply from the next token refresh.
+func (a *Authenticator) PermissionMiddleware(module, action string) echo.MiddlewareFunc {
+	return a.guard(permissionCheck(module, action))
+}
+
+// UserPermissionMiddleware is PermissionMiddleware for routes that act for the calling user, such as the cart
+// and orders. Machine clients have no user of their own (UserID is 0) and are refused whatever their scopes.
+func (a *Authenticator) UserPermissionMiddleware(module, action string) echo.MiddlewareFunc {
+	check := permissionCheck(module, action)
+	return a.guard(func(claims *Claims) *echo.HTTPError {
+		if claims.ClientID != "" {
+			return echo.NewHTTPError(http.StatusForbidden, "client tokens can not act for a user")
+		}
+		return check(claims)
+	})
+}
+
+func permissionCheck(module, action string) func(*Claims) *echo.HTTPError {
+	permission := fmt.Sprintf("%s:%s", module, action)
+	return func(claims *Claims) *echo.HTTPError {
+		if !hasPermission(claims.Perms, permission) {
+			return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("missing permission %s", permission))
+		}
+		return nil
+	}
+}
+
+// guard authenticates the request, applies check and stores the caller in the context. An impersonation
+// token acts with the impersonated user's claims and every request made with it, allowed or not, is audited.
+func (a *Authenticator) guard(check func(*Claims) *echo.HTTPError) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			claims, httpErr := a.Authenticate(c)
+			if httpErr != nil {
+				return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+			}
+			defer func() { a.auditImpersonation(c, claims, err) }()
+
+			if httpErr := check(claims); httpErr != nil {
+				return c.JSON(httpErr.Code, echo.Map{"error": httpErr.Message})
+			}
+
+			SetContext(c, claims)
+			return next(c)
+		}
+	}
+}
+
+// Authenticate reads the token from the configured headers and checks its signature, expiry and revocation
+func (a *Authenticator) Authenticate(c echo.Context) (*Claims, *echo.HTTPError) {
+	token := TokenFromRequest(c.Request(), a.cfg.Headers)
+	if token == "" {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, fmt.Sprintf("missing %s header", strings.Join(a.cfg.Headers, " or ")))
+	}
+
+	claims := a.cache.get(token)
+	if claims == nil {
+		claims = &Claims{}
+		if err := a.cfg.Parse(token, claims); err != nil {
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired token")
+		}
+		a.cache.put(token, claims)
+	}
+
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "token expired")
+	}
+
+	revoked, err := IsRevoked(a.cfg.Redis, claims)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to check token status")
+	}
+	if revoked {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "token revoked")
+	}
+
+	return claims, nil
+}
+
+// parseWithJWKS verifies a token against the public keys auth-service publishes
+func parseWithJWKS(tokenString string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc, jwt.WithValidMethods(signingMethods))
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return fmt.Errorf("invalid token")
+	}
+	return nil
+}
+
+// Helper to check granted permissions
+func hasPermission(perms []string, permission string) bool {
+	for _, p := range perms {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper to check allowed roles
+func isRoleAllowed(userRole string, allowedRoles []string) bool {
+	for _, r := range allowedRoles {
+		if strings.EqualFold(r, "all") || strings.EqualFold(userRole, r) {
+			return true
+		}
+	}
+	return false
+}
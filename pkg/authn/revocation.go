@@ -0,0 +1,54 @@
+package authn
+
+import (
+	"fmt"
+
+	"github.com/go-redis/redis"
+)
+
+// The denylist is written by auth-service and read by every service, so all of them share one Redis.
+
+// RevokedTokenKey marks a single access token (by jti) as revoked
+func RevokedTokenKey(jti string) string { return fmt.Sprintf("revoked:jti:%s", jti) }
+
+// RevokedUserKey holds the unix time before which every access token of the user is revoked
+func RevokedUserKey(userID int64) string { return fmt.Sprintf("revoked:user:%d", userID) }
+
+// RevokedSessionKey marks every access token of a session (sid claim, the refresh token family) as revoked
+func RevokedSessionKey(sid string) string { return fmt.Sprintf("revoked:sid:%s", sid) }
+
+// RevokedClientKey holds the unix time before which every access token of the machine client is revoked
+func RevokedClientKey(clientID string) string { return fmt.Sprintf("revoked:client:%s", clientID) }
+
+// IsRevoked checks the denylist for the token jti, its session and a user (or machine client) wide revocation
+func IsRevoked(conn *redis.Client, claims *Claims) (bool, error) {
+	keys := []string{}
+	if claims.ID != "" {
+		keys = append(keys, RevokedTokenKey(claims.ID))
+	}
+	if claims.SessionID != "" {
+		keys = append(keys, RevokedSessionKey(claims.SessionID))
+	}
+	if len(keys) > 0 {
+		n, err := conn.Exists(keys...).Result()
+		if err != nil {
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+
+	revokedBeforeKey := RevokedUserKey(claims.UserID)
+	if claims.ClientID != "" {
+		revokedBeforeKey = RevokedClientKey(claims.ClientID)
+	}
+	revokedAt, err := conn.Get(revokedBeforeKey).Int64()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return claims.IssuedAt == nil || claims.IssuedAt.Unix() <= revokedAt, nil
+}
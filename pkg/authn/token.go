@@ -0,0 +1,32 @@
+package authn
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultHeaders are the headers a token is read from by default: the api-key header the clients send
+// today, then the standard Authorization header
+var DefaultHeaders = []string{"api-key", echo.HeaderAuthorization}
+
+// TokenFromRequest returns the token of the first of headers that carries one. The Authorization header
+// holds it after the Bearer scheme, any other header holds the bare token.
+func TokenFromRequest(r *http.Request, headers []string) string {
+	for _, h := range headers {
+		v := strings.TrimSpace(r.Header.Get(h))
+		if v == "" {
+			continue
+		}
+		if strings.EqualFold(h, echo.HeaderAuthorization) {
+			scheme, token, ok := strings.Cut(v, " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") {
+				continue
+			}
+			return strings.TrimSpace(token)
+		}
+		return v
+	}
+	return ""
+}
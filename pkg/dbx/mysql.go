@@ -0,0 +1,45 @@
+// Package dbx opens the MySQL databases of the services.
+package dbx
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+// Open connects to the database dbname on DB_HOST:DB_PORT as DB_USERNAME, connection errors are logged
+func Open(dbname string) *sql.DB {
+	dbURI := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=True&multiStatements=true",
+		os.Getenv("DB_USERNAME"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), dbname, "utf8")
+	db, err := sql.Open("mysql", dbURI)
+	checkErr(err)
+
+	err = db.Ping()
+	checkErr(err)
+	return db
+}
+
+// CheckConnectionStatus pings the database for the status endpoints
+func CheckConnectionStatus() (int, interface{}) {
+	res := make(map[string]interface{})
+
+	db := Open(os.Getenv("IDENTITY_DB_NAME"))
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		res["db_status"] = err.Error()
+		return http.StatusInternalServerError, res
+	}
+	res["db_status"] = "sent successful ping"
+	return http.StatusOK, res
+}
+
+func checkErr(err error) {
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"description": "got error connecting to db"}).Error(err.Error())
+	}
+}
@@ -0,0 +1,26 @@
+// Package mq connects to RabbitMQ and publishes the events the services exchange.
+package mq
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Dial connects to the broker described by RABBITMQ_HOST, _PORT, _USER, _PASS and _VHOST, nil when it
+// can not be reached
+func Dial() *amqp.Connection {
+	host := os.Getenv("RABBITMQ_HOST")
+	port := os.Getenv("RABBITMQ_PORT")
+	vhost := os.Getenv("RABBITMQ_VHOST")
+
+	uri := fmt.Sprintf("amqp://%s:%s@%s:%s/%s", os.Getenv("RABBITMQ_USER"), os.Getenv("RABBITMQ_PASS"), host, port, vhost)
+	conn, err := amqp.Dial(uri)
+	if err != nil {
+		log.Printf("got error connecting to rabbitMQ %s at %s:%s/%s", err.Error(), host, port, vhost)
+		return nil
+	}
+	return conn
+}
@@ -0,0 +1,81 @@
+package mq
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// EventVersion is the version of the envelope and of the payloads it carries. It is bumped when a
+// payload changes in a way old consumers can not read; adding fields does not need a bump.
+const EventVersion = 1
+
+// Envelope wraps every published event, consumers switch on Type and Version and unmarshal Data into
+// the payload of that event
+type Envelope struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	Version    int         `json:"version"`
+	Source     string      `json:"source"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Publisher publishes the events of one service on a topic exchange
+type Publisher struct {
+	ch       *amqp.Channel
+	exchange string
+	source   string
+}
+
+// NewPublisher opens a publishing channel on conn and declares the topic exchange, source names the
+// publishing service in every envelope
+func NewPublisher(conn *amqp.Connection, exchange, source string) (*Publisher, error) {
+	if conn == nil {
+		return nil, fmt.Errorf("no rabbitMQ connection")
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	if err := ch.ExchangeDeclare(exchange, "topic", true, false, false, false, nil); err != nil {
+		log.Printf("exchange declare failed: %v", err)
+	}
+	return &Publisher{ch: ch, exchange: exchange, source: source}, nil
+}
+
+// Publish sends payload wrapped in an Envelope, the routing key is the event type
+func (p *Publisher) Publish(routing string, payload interface{}) error {
+	if p == nil {
+		return fmt.Errorf("publisher is not connected")
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	envelope := Envelope{
+		ID:         hex.EncodeToString(id),
+		Type:       routing,
+		Version:    EventVersion,
+		Source:     p.source,
+		OccurredAt: time.Now().UTC(),
+		Data:       payload,
+	}
+	b, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	return p.ch.Publish(p.exchange, routing, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    envelope.ID,
+		Type:         envelope.Type,
+		Timestamp:    envelope.OccurredAt,
+		Body:         b,
+	})
+}
@@ -0,0 +1,24 @@
+// Package redisx connects to the Redis shared by the services and wraps the key operations they use.
+package redisx
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// NewClient connects to REDIS_HOST:REDIS_PORT, authenticating with REDIS_AUTH when it is set
+func NewClient() *redis.Client {
+	opts := redis.Options{
+		MinIdleConns: 10,
+		IdleTimeout:  60 * time.Second,
+		PoolSize:     1000,
+		Addr:         fmt.Sprintf("%s:%s", os.Getenv("REDIS_HOST"), os.Getenv("REDIS_PORT")),
+	}
+	if auth := os.Getenv("REDIS_AUTH"); len(auth) > 0 {
+		opts.Password = auth
+	}
+	return redis.NewClient(&opts)
+}
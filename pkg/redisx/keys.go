@@ -0,0 +1,82 @@
+package redisx
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Get returns the value of key
+func Get(conn *redis.Client, key string) (string, error) {
+	data, err := conn.Get(key).Result()
+	if err != nil {
+		return data, fmt.Errorf("error getting key %s: %v", key, err)
+	}
+	return data, nil
+}
+
+// Set stores value under key without expiry
+func Set(conn *redis.Client, key string, value string) error {
+	return SetWithExpiry(conn, key, value, 0)
+}
+
+// SetWithExpiry stores value under key for seconds, forever when seconds is 0
+func SetWithExpiry(conn *redis.Client, key string, value string, seconds int) error {
+	if err := conn.Set(key, value, time.Second*time.Duration(seconds)).Err(); err != nil {
+		if len(value) > 15 {
+			value = value[0:12] + "..."
+		}
+		return fmt.Errorf("error setting key %s to %s: %v", key, value, err)
+	}
+	return nil
+}
+
+// Delete removes key
+func Delete(conn *redis.Client, key string) error {
+	if err := conn.Del(key).Err(); err != nil {
+		return fmt.Errorf("error deleting key %s: %v", key, err)
+	}
+	return nil
+}
+
+// GetAll returns the values of every key matching pattern, keys that vanish while reading are skipped
+func GetAll(conn *redis.Client, pattern string) (map[string]string, error) {
+	keys, err := conn.Keys(pattern).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	results := map[string]string{}
+	for _, k := range keys {
+		v, err := conn.Get(k).Result()
+		if err != nil {
+			continue
+		}
+		results[k] = v
+	}
+	return results, nil
+}
+
+// Incr increments the counter under key and returns its new value
+func Incr(conn *redis.Client, key string) (int64, error) {
+	n, err := conn.Incr(key).Result()
+	if err != nil {
+		return n, fmt.Errorf("error incrementing key %s: %v", key, err)
+	}
+	return n, nil
+}
+
+// Consume reads and deletes a key in one transaction so its value can only be used once
+func Consume(conn *redis.Client, key string) (string, error) {
+	var get *redis.StringCmd
+	_, err := conn.TxPipelined(func(pipe redis.Pipeliner) error {
+		get = pipe.Get(key)
+		pipe.Del(key)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error consuming key %s: %v", key, err)
+	}
+	return get.Val(), nil
+}